@@ -34,13 +34,45 @@ func main() {
 	}
 	defer p.Close()
 
-	repo := repository.NewAdminPanelRepository(domainRepository.NewAlbumRepository(db), domainRepository.NewLogsRepository(db))
-	useCase := usecase.NewAdminPanelUseCase(repo, conf.ProfilePort, p)
+	c, err := kafka.NewManualCommitConsumer(fmt.Sprintf("kafka:%s", conf.KafkaPort), "admin-panel")
+	if err != nil {
+		log.Fatalf("unable to create consumer: %s", err.Error())
+	}
+	defer c.Close()
+
+	if err = c.SubscribeTopics([]string{kafka.DeadLetterTopic("money-operations"), kafka.DeadLetterTopic("notifications")}); err != nil {
+		log.Fatalf("unable to subscribe to topic %s", err.Error())
+	}
+
+	repo := repository.NewAdminPanelRepository(
+		domainRepository.NewArtistRepository(db),
+		domainRepository.NewAlbumRepository(db),
+		domainRepository.NewLogsRepository(db),
+		domainRepository.NewDeadLetterRepository(db),
+		domainRepository.NewOrderRepository(db),
+		domainRepository.NewExchangeRateRepository(db),
+	)
+	useCase := usecase.NewAdminPanelUseCase(repo, conf.ProfilePort, p, c)
 	handler := handler.NewAdminPanelHandler(useCase)
 
 	router := gin.Default()
 	router.GET("/logs/:pageNumber", handler.HandleBuyLogs)
 	router.DELETE("/delete/:id", handler.HandleDeleteAlbum)
+	router.POST("/artists", handler.HandleAddArtist)
+	router.PUT("/artists/:id", handler.HandleUpdateArtist)
+	router.POST("/albums", handler.HandleAddAlbum)
+	router.PUT("/albums/:id", handler.HandleUpdateAlbum)
+	router.POST("/albums/:id/tracks", handler.HandleAddTrack)
+	router.PUT("/tracks/:id", handler.HandleUpdateTrack)
+	router.GET("/dead-letters/:pageNumber", handler.HandleDeadLetters)
+	router.POST("/dead-letters/republish", handler.HandleRepublish)
+	router.POST("/orders/:id/refund", handler.HandleRefundOrder)
+	router.GET("/rates", handler.HandleExchangeRates)
+	router.POST("/rates", handler.HandleLoadExchangeRates)
+	router.PUT("/rates/:currency", handler.HandleSetExchangeRate)
+	router.DELETE("/rates/:currency", handler.HandleDeleteExchangeRate)
+
+	go useCase.ConsumeDeadLetters()
 
 	log.Fatal(http.ListenAndServe(":"+conf.AdminPanelPort, router))
 }
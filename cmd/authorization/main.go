@@ -50,8 +50,13 @@ func main() {
 	router := gin.Default()
 	router.GET("/authorize", handler.HandleAuthorization)
 	router.GET("/authenticate", handler.HandleAuthentication)
+	router.POST("/refresh", handler.HandleRefresh)
 	router.POST("/logout", handler.HandleLogout)
 	router.POST("/registration", handler.HandleRegistration)
+	router.GET("/sessions", handler.HandleSessions)
+	router.DELETE("/sessions", handler.HandleRevokeAllSessions)
+	router.DELETE("/sessions/:id", handler.HandleRevokeSession)
+	router.DELETE("/users/:id/sessions", handler.HandleRevokeUserSessions)
 
 	log.Fatal(http.ListenAndServe(":"+conf.AuthorizationPort, router))
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,7 +9,9 @@ import (
 	"github.com/allnightmarel0Ng/albums/internal/app/gateway/handler"
 	"github.com/allnightmarel0Ng/albums/internal/app/gateway/usecase"
 	"github.com/allnightmarel0Ng/albums/internal/config"
+	"github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/redis"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,33 +27,82 @@ func main() {
 	}
 	defer p.Close()
 
-	useCase := usecase.NewGatewayUseCase(p, conf.AuthorizationPort, conf.ProfilePort, conf.OrderManagementPort, conf.SearchEnginePort, conf.AdminPanelPort, conf.JwtSecretKey, conf.PostgresUser, conf.PostgresPassword, conf.PostgresPort, conf.PostgresDb)
+	client := redis.NewClient(fmt.Sprintf("redis:%s", conf.RedisPort), "", 0)
+	if client == nil {
+		log.Fatal("unable to connect to redis")
+	}
+
+	defer func() {
+		err = client.Close()
+		if err != nil {
+			log.Fatalf("unable to close redis connection: %s", err.Error())
+		}
+	}()
+	if err = client.Ping(context.Background()); err != nil {
+		log.Fatalf("unable to ping redis: %s", err.Error())
+	}
+
+	useCase := usecase.NewGatewayUseCase(p, repository.NewOperationRepository(client), conf.AuthorizationPort, conf.ProfilePort, conf.OrderManagementPort, conf.SearchEnginePort, conf.AdminPanelPort, conf.NotificationsPort, conf.JwtSecretKey, conf.PostgresUser, conf.PostgresPassword, conf.PostgresPort, conf.PostgresDb)
 	handler := handler.NewGatewayHandler(useCase)
 
 	router := gin.Default()
 
 	router.GET("/login", handler.HandleLogin)
+	router.POST("/refresh", handler.HandleRefresh)
 	router.POST("/logout", handler.HandleLogout)
 	router.POST("/registration", handler.HandleRegistration)
+	router.GET("/sessions", handler.HandleSessions)
+	router.DELETE("/sessions", handler.HandleRevokeAllSessions)
+	router.DELETE("/sessions/:id", handler.HandleRevokeSession)
 
 	router.POST("/", handler.HandleMainPage)
+	router.GET("/search", handler.HandleSearch)
 	router.POST("/search", handler.HandleSearch)
+	router.GET("/suggest", handler.HandleSuggest)
+	router.GET("/charts", handler.HandleChart)
+	router.GET("/charts/artists/:id", handler.HandleArtistChartPositions)
 
 	router.GET("/profile", handler.HandleUserProfile)
+	router.GET("/profile/transactions", handler.HandleTransactions)
+	router.PUT("/profile/currency", handler.HandleChangeCurrency)
+	router.GET("/rates", handler.HandleExchangeRates)
 	router.GET("/artists/:id", handler.HandleArtistProfile)
 	router.GET("/albums/:id", handler.HandleAlbumProfile)
 
 	router.POST("/add/:id", handler.HandleOrderAdd)
 	router.POST("/remove/:id", handler.HandleOrderRemove)
 	router.GET("/orders", handler.HandleOrders)
+	router.DELETE("/orders", handler.HandleCancelOrder)
+	router.GET("/wishlist", handler.HandleWishlist)
+	router.POST("/wishlist/order", handler.HandleMoveWishlist)
+	router.POST("/wishlist/:id", handler.HandleWishlistAdd)
+	router.DELETE("/wishlist/:id", handler.HandleWishlistRemove)
 
 	router.POST("/deposit", handler.HandleDeposit)
 	router.POST("/buy", handler.HandleBuy)
+	router.GET("/operations/:id", handler.HandleOperation)
+
+	router.GET("/notifications", handler.HandleNotifications)
+	router.PUT("/notifications/read", handler.HandleMarkNotificationsRead)
 
 	router.GET("/admin-panel/logs/:pageNumber", handler.HandleLogs)
 	router.DELETE("/admin-panel/delete/:id", handler.HandleDelete)
 	router.GET("/admin-panel/save-dump", handler.HandleSaveDump)
 	router.POST("/admin-panel/load-dump", handler.HandleLoadDump)
+	router.POST("/admin-panel/artists", handler.HandleAddArtist)
+	router.PUT("/admin-panel/artists/:id", handler.HandleUpdateArtist)
+	router.POST("/admin-panel/albums", handler.HandleAddAlbum)
+	router.PUT("/admin-panel/albums/:id", handler.HandleUpdateAlbum)
+	router.POST("/admin-panel/albums/:id/tracks", handler.HandleAddTrack)
+	router.PUT("/admin-panel/tracks/:id", handler.HandleUpdateTrack)
+	router.DELETE("/admin-panel/users/:id/sessions", handler.HandleRevokeUserSessions)
+	router.GET("/admin-panel/dead-letters/:pageNumber", handler.HandleDeadLetters)
+	router.POST("/admin-panel/dead-letters/republish", handler.HandleRepublish)
+	router.POST("/admin-panel/orders/:id/refund", handler.HandleRefundOrder)
+	router.GET("/admin-panel/rates", handler.HandleAdminExchangeRates)
+	router.POST("/admin-panel/rates", handler.HandleLoadExchangeRates)
+	router.PUT("/admin-panel/rates/:currency", handler.HandleSetExchangeRate)
+	router.DELETE("/admin-panel/rates/:currency", handler.HandleDeleteExchangeRate)
 
 	log.Fatal(http.ListenAndServe(":"+conf.GatewayPort, router))
 }
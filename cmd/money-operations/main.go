@@ -12,6 +12,7 @@ import (
 	domainRepository "github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/redis"
 )
 
 func main() {
@@ -20,7 +21,7 @@ func main() {
 		log.Fatalf("unable to load config: %s", err.Error())
 	}
 
-	c, err := kafka.NewConsumer(fmt.Sprintf("kafka:%s", conf.KafkaPort), "consumers")
+	c, err := kafka.NewManualCommitConsumer(fmt.Sprintf("kafka:%s", conf.KafkaPort), "consumers")
 	if err != nil {
 		log.Fatalf("unable to create consumer: %s", err.Error())
 	}
@@ -36,13 +37,33 @@ func main() {
 	}
 	defer p.Close()
 
+	c.EnableDeadLettering(p, kafka.RetryPolicy{
+		MaxRetries: conf.KafkaMaxRetries,
+		Backoff:    conf.KafkaRetryBackoff,
+	})
+
 	db, err := postgres.NewDatabase(context.Background(), fmt.Sprintf("postgresql://%s:%s@postgres:%s/%s?sslmode=disable", conf.PostgresUser, conf.PostgresPassword, conf.PostgresPort, conf.PostgresDb))
 	if err != nil {
 		log.Fatalf("unable to establish db connection: %s", err.Error())
 	}
 	defer db.Close()
 
-	repo := repository.NewMoneyOperationsRepository(domainRepository.NewUserRepository(db))
+	client := redis.NewClient(fmt.Sprintf("redis:%s", conf.RedisPort), "", 0)
+	if client == nil {
+		log.Fatal("unable to connect to redis")
+	}
+
+	defer func() {
+		err = client.Close()
+		if err != nil {
+			log.Fatalf("unable to close redis connection: %s", err.Error())
+		}
+	}()
+	if err = client.Ping(context.Background()); err != nil {
+		log.Fatalf("unable to ping redis: %s", err.Error())
+	}
+
+	repo := repository.NewMoneyOperationsRepository(domainRepository.NewUserRepository(db), domainRepository.NewOperationRepository(client))
 	useCase := usecase.NewMoneyOperationsUseCase(repo, p)
 	handler := handler.NewMoneyOperationsHandler(useCase, c)
 	handler.Handle()
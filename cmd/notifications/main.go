@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/allnightmarel0Ng/albums/internal/app/notifications/handler"
+	"github.com/allnightmarel0Ng/albums/internal/app/notifications/repository"
 	"github.com/allnightmarel0Ng/albums/internal/app/notifications/usecase"
 	"github.com/allnightmarel0Ng/albums/internal/config"
+	domainRepository "github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/redis"
+	"github.com/gin-gonic/gin"
 )
 
 func main() {
@@ -17,7 +23,28 @@ func main() {
 		log.Fatalf("unable to load config: %s", err.Error())
 	}
 
-	c, err := kafka.NewConsumer(fmt.Sprintf("kafka:%s", conf.KafkaPort), "consumers")
+	db, err := postgres.NewDatabase(context.Background(), fmt.Sprintf("postgresql://%s:%s@postgres:%s/%s?sslmode=disable", conf.PostgresUser, conf.PostgresPassword, conf.PostgresPort, conf.PostgresDb))
+	if err != nil {
+		log.Fatalf("unable to establish db connection: %s", err.Error())
+	}
+	defer db.Close()
+
+	client := redis.NewClient(fmt.Sprintf("redis:%s", conf.RedisPort), "", 0)
+	if client == nil {
+		log.Fatal("unable to connect to redis")
+	}
+
+	defer func() {
+		err = client.Close()
+		if err != nil {
+			log.Fatalf("unable to close redis connection: %s", err.Error())
+		}
+	}()
+	if err = client.Ping(context.Background()); err != nil {
+		log.Fatalf("unable to ping redis: %s", err.Error())
+	}
+
+	c, err := kafka.NewManualCommitConsumer(fmt.Sprintf("kafka:%s", conf.KafkaPort), "consumers")
 	if err != nil {
 		log.Fatalf("unable to create consumer: %s", err.Error())
 	}
@@ -27,12 +54,29 @@ func main() {
 		log.Fatalf("unable to subscribe to topic %s", err.Error())
 	}
 
-	useCase := usecase.NewNotificationsUseCase(c)
+	p, err := kafka.NewProducer(fmt.Sprintf("kafka:%s", conf.KafkaPort), 4)
+	if err != nil {
+		log.Fatalf("unable to create a producer: %s", err.Error())
+	}
+	defer p.Close()
+
+	c.EnableDeadLettering(p, kafka.RetryPolicy{
+		MaxRetries: conf.KafkaMaxRetries,
+		Backoff:    conf.KafkaRetryBackoff,
+	})
+
+	repo := repository.NewNotificationsRepository(domainRepository.NewNotificationRepository(db), client)
+	useCase := usecase.NewNotificationsUseCase(repo, c)
 	handler := handler.NewNotificationsHandler(useCase, conf.AuthorizationPort)
 
-	http.HandleFunc("/ws", handler.HandleNotifications)
+	router := gin.Default()
+	router.GET("/ws", gin.WrapF(handler.HandleNotifications))
+	router.GET("/events", handler.HandleEvents)
+	router.GET("/users/:id/notifications", handler.HandleUserNotifications)
+	router.PUT("/users/:id/notifications/read", handler.HandleMarkRead)
 
 	go useCase.Consume()
+	go useCase.Deliver()
 
-	log.Fatal(http.ListenAndServe(":"+conf.NotificationsPort, nil))
+	log.Fatal(http.ListenAndServe(":"+conf.NotificationsPort, router))
 }
@@ -27,7 +27,7 @@ func main() {
 	}
 	defer db.Close()
 
-	repo := repository.NewOrderManagementRepository(domainRepository.NewOrderRepository(db))
+	repo := repository.NewOrderManagementRepository(domainRepository.NewOrderRepository(db), domainRepository.NewWishlistRepository(db), domainRepository.NewAlbumRepository(db))
 	useCase := usecase.NewOrderManagementUseCase(repo)
 	handler := handler.NewOrderManagementHandler(useCase)
 
@@ -35,6 +35,11 @@ func main() {
 	router.POST("/add", handler.HandleAdd)
 	router.POST("/remove", handler.HandleRemove)
 	router.GET("/orders/:id", handler.HandleOrders)
+	router.DELETE("/orders/:id", handler.HandleCancel)
+	router.POST("/wishlist/add", handler.HandleWishlistAdd)
+	router.POST("/wishlist/remove", handler.HandleWishlistRemove)
+	router.GET("/wishlist/:id", handler.HandleWishlist)
+	router.POST("/wishlist/:id/order", handler.HandleMoveWishlist)
 
 	log.Fatal(http.ListenAndServe(":"+conf.OrderManagementPort, router))
 }
@@ -31,6 +31,8 @@ func main() {
 		domainRepository.NewUserRepository(db),
 		domainRepository.NewAlbumRepository(db),
 		domainRepository.NewArtistRepository(db),
+		domainRepository.NewLedgerRepository(db),
+		domainRepository.NewExchangeRateRepository(db),
 	)
 	usecase := usecase.NewProfileUseCase(repo)
 	handler := handler.NewProfileHandler(usecase)
@@ -40,6 +42,9 @@ func main() {
 	router.GET("/artists/:id", handler.HandleArtistProfile)
 	router.GET("/albums/:id", handler.HandleAlbumProfile)
 	router.GET("/owners/:id", handler.HandleOwners)
+	router.GET("/users/:id/transactions", handler.HandleTransactions)
+	router.PUT("/users/:id/currency", handler.HandleChangeCurrency)
+	router.GET("/rates", handler.HandleExchangeRates)
 
 	log.Fatal(http.ListenAndServe(":"+conf.ProfilePort, router))
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/allnightmarel0Ng/albums/internal/app/search-engine/usecase"
 	"github.com/allnightmarel0Ng/albums/internal/config"
 	domainRepository "github.com/allnightmarel0Ng/albums/internal/domain/repository"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
 	"github.com/gin-gonic/gin"
 )
@@ -27,13 +28,32 @@ func main() {
 	}
 	defer db.Close()
 
-	repo := repository.NewSearchEngineRepository(domainRepository.NewArtistRepository(db), domainRepository.NewAlbumRepository(db))
-	usecase := usecase.NewSearchEngineUseCase(repo)
+	c, err := kafka.NewBroadcastConsumer(fmt.Sprintf("kafka:%s", conf.KafkaPort), "search-engine")
+	if err != nil {
+		log.Fatalf("unable to create consumer: %s", err.Error())
+	}
+	defer c.Close()
+
+	if err = c.SubscribeTopics([]string{"catalog"}); err != nil {
+		log.Fatalf("unable to subscribe to topic %s", err.Error())
+	}
+
+	repo := repository.NewSearchEngineRepository(domainRepository.NewArtistRepository(db), domainRepository.NewAlbumRepository(db), domainRepository.NewSearchRepository(db), domainRepository.NewRecommendationRepository(db), domainRepository.NewChartRepository(db))
+	usecase := usecase.NewSearchEngineUseCase(repo, c)
 	handler := handler.NewSearchEngineHandler(usecase)
 
 	router := gin.Default()
 	router.POST("/random", handler.HandleRandom)
+	router.POST("/recommendations", handler.HandleRecommendations)
+	router.GET("/search", handler.HandleSearch)
 	router.POST("/search", handler.HandleSearch)
+	router.GET("/suggest", handler.HandleSuggest)
+	router.GET("/charts", handler.HandleChart)
+	router.GET("/charts/artists/:id", handler.HandleArtistChartPositions)
+
+	go usecase.KeepSuggestionsFresh()
+	go usecase.RefreshCoPurchasesEternally()
+	go usecase.RefreshChartsEternally()
 
 	log.Fatal(http.ListenAndServe(":"+conf.SearchEnginePort, router))
 }
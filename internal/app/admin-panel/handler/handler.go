@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/allnightmarel0Ng/albums/internal/app/admin-panel/usecase"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
@@ -13,6 +15,19 @@ import (
 type AdminPanelHandler interface {
 	HandleBuyLogs(c *gin.Context)
 	HandleDeleteAlbum(c *gin.Context)
+	HandleAddArtist(c *gin.Context)
+	HandleUpdateArtist(c *gin.Context)
+	HandleAddAlbum(c *gin.Context)
+	HandleUpdateAlbum(c *gin.Context)
+	HandleAddTrack(c *gin.Context)
+	HandleUpdateTrack(c *gin.Context)
+	HandleDeadLetters(c *gin.Context)
+	HandleRepublish(c *gin.Context)
+	HandleRefundOrder(c *gin.Context)
+	HandleExchangeRates(c *gin.Context)
+	HandleSetExchangeRate(c *gin.Context)
+	HandleLoadExchangeRates(c *gin.Context)
+	HandleDeleteExchangeRate(c *gin.Context)
 }
 
 type adminPanelHandler struct {
@@ -26,22 +41,172 @@ func NewAdminPanelHandler(useCase usecase.AdminPanelUseCase) AdminPanelHandler {
 }
 
 func (a *adminPanelHandler) HandleBuyLogs(c *gin.Context) {
+	pageNumber, pageSize, ok := getPage(c)
+	if !ok {
+		return
+	}
+
+	utils.Send(c, a.useCase.Logs(pageNumber, pageSize))
+}
+
+func (a *adminPanelHandler) HandleDeleteAlbum(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid 'id' parameter",
+		})
+		return
+	}
+
+	response := a.useCase.DeleteAlbum(id)
+	if response != nil {
+		utils.Send(c, response)
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
+
+func (a *adminPanelHandler) HandleAddArtist(c *gin.Context) {
+	var request api.ArtistRequest
+	if !bindRequest(c, &request) {
+		return
+	}
+
+	utils.Send(c, a.useCase.AddArtist(request))
+}
+
+func (a *adminPanelHandler) HandleUpdateArtist(c *gin.Context) {
+	var request api.ArtistRequest
+	id, ok := bindRequestWithID(c, &request)
+	if !ok {
+		return
+	}
+
+	sendOrOK(c, a.useCase.UpdateArtist(id, request))
+}
+
+func (a *adminPanelHandler) HandleAddAlbum(c *gin.Context) {
+	var request api.AlbumRequest
+	if !bindRequest(c, &request) {
+		return
+	}
+
+	utils.Send(c, a.useCase.AddAlbum(request))
+}
+
+func (a *adminPanelHandler) HandleUpdateAlbum(c *gin.Context) {
+	var request api.AlbumRequest
+	id, ok := bindRequestWithID(c, &request)
+	if !ok {
+		return
+	}
+
+	sendOrOK(c, a.useCase.UpdateAlbum(id, request))
+}
+
+func (a *adminPanelHandler) HandleAddTrack(c *gin.Context) {
+	var request api.TrackRequest
+	id, ok := bindRequestWithID(c, &request)
+	if !ok {
+		return
+	}
+
+	utils.Send(c, a.useCase.AddTrack(id, request))
+}
+
+func (a *adminPanelHandler) HandleUpdateTrack(c *gin.Context) {
+	var request api.TrackRequest
+	id, ok := bindRequestWithID(c, &request)
+	if !ok {
+		return
+	}
+
+	sendOrOK(c, a.useCase.UpdateTrack(id, request))
+}
+
+func (a *adminPanelHandler) HandleDeadLetters(c *gin.Context) {
+	pageNumber, pageSize, ok := getPage(c)
+	if !ok {
+		return
+	}
+
+	utils.Send(c, a.useCase.DeadLetters(pageNumber, pageSize))
+}
+
+func (a *adminPanelHandler) HandleRepublish(c *gin.Context) {
+	var request api.RepublishRequest
+	if !bindRequest(c, &request) {
+		return
+	}
+
+	utils.Send(c, a.useCase.Republish(request.IDs))
+}
+
+func (a *adminPanelHandler) HandleRefundOrder(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid 'id' parameter",
+		})
+		return
+	}
+
+	sendOrOK(c, a.useCase.RefundOrder(id))
+}
+
+func (a *adminPanelHandler) HandleExchangeRates(c *gin.Context) {
+	utils.Send(c, a.useCase.ExchangeRates())
+}
+
+func (a *adminPanelHandler) HandleSetExchangeRate(c *gin.Context) {
+	var request api.ExchangeRateRequest
+	if !bindRequest(c, &request) {
+		return
+	}
+
+	sendOrOK(c, a.useCase.SetExchangeRates(map[string]json.Number{
+		strings.ToUpper(c.Param("currency")): request.Rate,
+	}))
+}
+
+func (a *adminPanelHandler) HandleLoadExchangeRates(c *gin.Context) {
+	var request api.ExchangeRatesRequest
+	if !bindRequest(c, &request) {
+		return
+	}
+
+	rates := make(map[string]json.Number, len(request.Rates))
+	for currency, rate := range request.Rates {
+		rates[strings.ToUpper(currency)] = rate
+	}
+
+	sendOrOK(c, a.useCase.SetExchangeRates(rates))
+}
+
+func (a *adminPanelHandler) HandleDeleteExchangeRate(c *gin.Context) {
+	sendOrOK(c, a.useCase.DeleteExchangeRate(strings.ToUpper(c.Param("currency"))))
+}
+
+func getPage(c *gin.Context) (uint, uint, bool) {
 	paramStr, ok := c.Params.Get("pageNumber")
 	if !ok {
 		utils.Send(c, &api.ErrorResponse{
 			Code:  http.StatusBadRequest,
 			Error: "invalid 'pageNumber' parameter",
 		})
-		return
+		return 0, 0, false
 	}
 
 	pageNumber, err := strconv.ParseUint(paramStr, 10, 64)
-	if err != nil {
+	if err != nil || pageNumber == 0 {
 		utils.Send(c, &api.ErrorResponse{
 			Code:  http.StatusBadRequest,
 			Error: "invalid 'pageNumber' parameter",
 		})
-		return
+		return 0, 0, false
 	}
 
 	pageSizeStr := c.DefaultQuery("pageSize", "10")
@@ -51,23 +216,37 @@ func (a *adminPanelHandler) HandleBuyLogs(c *gin.Context) {
 			Code:  http.StatusBadRequest,
 			Error: "invalid 'pageSize' parameter",
 		})
-		return
+		return 0, 0, false
 	}
 
-	utils.Send(c, a.useCase.Logs(uint(pageNumber), uint(pageSize)))
+	return uint(pageNumber), uint(pageSize), true
 }
 
-func (a *adminPanelHandler) HandleDeleteAlbum(c *gin.Context) {
+func bindRequest(c *gin.Context, request interface{}) bool {
+	if err := c.ShouldBindJSON(request); err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid request fields",
+		})
+		return false
+	}
+	return true
+}
+
+func bindRequestWithID(c *gin.Context, request interface{}) (int, bool) {
 	id, err := utils.GetParam(c, "id")
 	if err != nil {
 		utils.Send(c, &api.ErrorResponse{
 			Code:  http.StatusBadRequest,
 			Error: "invalid 'id' parameter",
 		})
-		return
+		return 0, false
 	}
 
-	response := a.useCase.DeleteAlbum(id)
+	return id, bindRequest(c, request)
+}
+
+func sendOrOK(c *gin.Context, response api.Response) {
 	if response != nil {
 		utils.Send(c, response)
 		return
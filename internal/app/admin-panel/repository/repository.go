@@ -10,17 +10,39 @@ import (
 type AdminPanelRepository interface {
 	GetBuyLogsAndCount(ctx context.Context, offset, limit uint) (uint, []model.BuyLog, error)
 	DeleteAlbum(ctx context.Context, albumID int) error
+	AddArtist(ctx context.Context, artist model.Artist) (int, error)
+	UpdateArtist(ctx context.Context, artist model.Artist) error
+	AddAlbum(ctx context.Context, album model.Album) (int, error)
+	UpdateAlbum(ctx context.Context, album model.Album) error
+	AddTrack(ctx context.Context, albumID int, track model.Track) (int, error)
+	UpdateTrack(ctx context.Context, track model.Track) error
+	AddDeadLetter(ctx context.Context, letter model.DeadLetter) error
+	GetDeadLettersAndCount(ctx context.Context, offset, limit uint) (uint, []model.DeadLetter, error)
+	ClaimDeadLetters(ctx context.Context, ids []int) ([]model.DeadLetter, error)
+	ReleaseDeadLetter(ctx context.Context, id int) error
+	GetOrder(ctx context.Context, orderID int) (model.Order, error)
+	GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error)
+	SetExchangeRates(ctx context.Context, rates []model.ExchangeRate) error
+	DeleteExchangeRate(ctx context.Context, currency string) error
 }
 
 type adminPanelRepository struct {
-	albums repository.AlbumRepository
-	logs   repository.LogsRepository
+	artists     repository.ArtistRepository
+	albums      repository.AlbumRepository
+	logs        repository.LogsRepository
+	deadLetters repository.DeadLetterRepository
+	orders      repository.OrderRepository
+	rates       repository.ExchangeRateRepository
 }
 
-func NewAdminPanelRepository(albums repository.AlbumRepository, logs repository.LogsRepository) AdminPanelRepository {
+func NewAdminPanelRepository(artists repository.ArtistRepository, albums repository.AlbumRepository, logs repository.LogsRepository, deadLetters repository.DeadLetterRepository, orders repository.OrderRepository, rates repository.ExchangeRateRepository) AdminPanelRepository {
 	return &adminPanelRepository{
-		albums: albums,
-		logs:   logs,
+		artists:     artists,
+		albums:      albums,
+		logs:        logs,
+		deadLetters: deadLetters,
+		orders:      orders,
+		rates:       rates,
 	}
 }
 
@@ -47,3 +69,135 @@ func (a *adminPanelRepository) DeleteAlbum(ctx context.Context, albumID int) err
 		return a.albums.DeleteAlbum(ctx, albumID)
 	}
 }
+
+func (a *adminPanelRepository) AddArtist(ctx context.Context, artist model.Artist) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		return a.artists.AddArtist(ctx, artist)
+	}
+}
+
+func (a *adminPanelRepository) UpdateArtist(ctx context.Context, artist model.Artist) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return a.artists.UpdateArtist(ctx, artist)
+	}
+}
+
+func (a *adminPanelRepository) AddAlbum(ctx context.Context, album model.Album) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		return a.albums.AddAlbum(ctx, album)
+	}
+}
+
+func (a *adminPanelRepository) UpdateAlbum(ctx context.Context, album model.Album) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return a.albums.UpdateAlbum(ctx, album)
+	}
+}
+
+func (a *adminPanelRepository) AddTrack(ctx context.Context, albumID int, track model.Track) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		return a.albums.AddTrack(ctx, albumID, track)
+	}
+}
+
+func (a *adminPanelRepository) UpdateTrack(ctx context.Context, track model.Track) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return a.albums.UpdateTrack(ctx, track)
+	}
+}
+
+func (a *adminPanelRepository) AddDeadLetter(ctx context.Context, letter model.DeadLetter) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return a.deadLetters.AddDeadLetter(ctx, letter)
+	}
+}
+
+func (a *adminPanelRepository) GetDeadLettersAndCount(ctx context.Context, offset, limit uint) (uint, []model.DeadLetter, error) {
+	select {
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	default:
+		letters, err := a.deadLetters.GetDeadLetters(ctx, offset, limit)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		count, err := a.deadLetters.GetDeadLettersCount(ctx)
+		return count, letters, err
+	}
+}
+
+func (a *adminPanelRepository) ClaimDeadLetters(ctx context.Context, ids []int) ([]model.DeadLetter, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		return a.deadLetters.ClaimDeadLetters(ctx, ids)
+	}
+}
+
+func (a *adminPanelRepository) ReleaseDeadLetter(ctx context.Context, id int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return a.deadLetters.ReleaseDeadLetter(ctx, id)
+	}
+}
+
+func (a *adminPanelRepository) GetOrder(ctx context.Context, orderID int) (model.Order, error) {
+	select {
+	case <-ctx.Done():
+		return model.Order{}, ctx.Err()
+	default:
+		return a.orders.GetOrderByID(ctx, orderID)
+	}
+}
+
+func (a *adminPanelRepository) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		return a.rates.GetExchangeRates(ctx)
+	}
+}
+
+func (a *adminPanelRepository) SetExchangeRates(ctx context.Context, rates []model.ExchangeRate) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return a.rates.SetExchangeRates(ctx, rates)
+	}
+}
+
+func (a *adminPanelRepository) DeleteExchangeRate(ctx context.Context, currency string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return a.rates.DeleteExchangeRate(ctx, currency)
+	}
+}
@@ -2,12 +2,16 @@ package usecase
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/allnightmarel0Ng/albums/internal/app/admin-panel/repository"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	domainRepository "github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
 )
@@ -15,19 +19,34 @@ import (
 type AdminPanelUseCase interface {
 	Logs(pageNumber uint, pageSize uint) api.Response
 	DeleteAlbum(albumID int) api.Response
+	AddArtist(request api.ArtistRequest) api.Response
+	UpdateArtist(artistID int, request api.ArtistRequest) api.Response
+	AddAlbum(request api.AlbumRequest) api.Response
+	UpdateAlbum(albumID int, request api.AlbumRequest) api.Response
+	AddTrack(albumID int, request api.TrackRequest) api.Response
+	UpdateTrack(trackID int, request api.TrackRequest) api.Response
+	DeadLetters(pageNumber uint, pageSize uint) api.Response
+	Republish(ids []int) api.Response
+	RefundOrder(orderID int) api.Response
+	ExchangeRates() api.Response
+	SetExchangeRates(rates map[string]json.Number) api.Response
+	DeleteExchangeRate(currency string) api.Response
+	ConsumeDeadLetters()
 }
 
 type adminPanelUseCase struct {
 	repo        repository.AdminPanelRepository
 	producer    *kafka.Producer
+	consumer    *kafka.Consumer
 	profilePort string
 }
 
-func NewAdminPanelUseCase(repo repository.AdminPanelRepository, profilePort string, producer *kafka.Producer) AdminPanelUseCase {
+func NewAdminPanelUseCase(repo repository.AdminPanelRepository, profilePort string, producer *kafka.Producer, consumer *kafka.Consumer) AdminPanelUseCase {
 	return &adminPanelUseCase{
 		repo:        repo,
 		profilePort: profilePort,
 		producer:    producer,
+		consumer:    consumer,
 	}
 }
 
@@ -62,13 +81,12 @@ func (a *adminPanelUseCase) DeleteAlbum(albumID int) api.Response {
 		var response api.AlbumOwnersResponse
 		err := json.Unmarshal(raw, &response)
 		if err == nil {
-			success := false
 			for i := 0; i < len(response.Ids); i++ {
 				err = utils.ProduceNotificationMessage(api.NotificationKafkaMessage{
 					Type:      api.Delete,
 					UserID:    response.Ids[i],
+					AlbumID:   albumID,
 					AlbumName: response.AlbumName,
-					Success:   &success,
 				}, a.producer)
 				if err != nil {
 					log.Printf("unable to produce the message: %s", err.Error())
@@ -87,5 +105,494 @@ func (a *adminPanelUseCase) DeleteAlbum(albumID int) api.Response {
 		}
 	}
 
+	a.catalogChanged(model.SearchEntityAlbum, albumID)
 	return nil
 }
+
+func (a *adminPanelUseCase) AddArtist(request api.ArtistRequest) api.Response {
+	if err := validateArtist(request); err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		}
+	}
+
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	id, err := a.repo.AddArtist(ctx, artistFromRequest(0, request))
+	if err != nil {
+		return catalogError(err)
+	}
+
+	a.catalogChanged(model.SearchEntityArtist, id)
+
+	return &api.CreatedResponse{
+		Code: http.StatusCreated,
+		ID:   id,
+	}
+}
+
+func (a *adminPanelUseCase) UpdateArtist(artistID int, request api.ArtistRequest) api.Response {
+	if err := validateArtist(request); err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		}
+	}
+
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	err := a.repo.UpdateArtist(ctx, artistFromRequest(artistID, request))
+	if err != nil {
+		return catalogError(err)
+	}
+
+	a.catalogChanged(model.SearchEntityArtist, artistID)
+
+	return nil
+}
+
+func (a *adminPanelUseCase) AddAlbum(request api.AlbumRequest) api.Response {
+	if err := validateAlbum(request); err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		}
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	id, err := a.repo.AddAlbum(ctx, albumFromRequest(0, request))
+	if err != nil {
+		return catalogError(err)
+	}
+
+	a.catalogChanged(model.SearchEntityAlbum, id)
+
+	return &api.CreatedResponse{
+		Code: http.StatusCreated,
+		ID:   id,
+	}
+}
+
+func (a *adminPanelUseCase) UpdateAlbum(albumID int, request api.AlbumRequest) api.Response {
+	if len(request.Tracks) != 0 {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "tracks of an existing album are edited one by one",
+		}
+	}
+
+	if err := validateAlbum(request); err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		}
+	}
+
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	err := a.repo.UpdateAlbum(ctx, albumFromRequest(albumID, request))
+	if err != nil {
+		return catalogError(err)
+	}
+
+	a.catalogChanged(model.SearchEntityAlbum, albumID)
+
+	return nil
+}
+
+func (a *adminPanelUseCase) AddTrack(albumID int, request api.TrackRequest) api.Response {
+	if err := validateTrack(request); err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		}
+	}
+
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	id, err := a.repo.AddTrack(ctx, albumID, trackFromRequest(0, request))
+	if err != nil {
+		return catalogError(err)
+	}
+
+	a.catalogChanged(model.SearchEntityTrack, id)
+
+	return &api.CreatedResponse{
+		Code: http.StatusCreated,
+		ID:   id,
+	}
+}
+
+func (a *adminPanelUseCase) UpdateTrack(trackID int, request api.TrackRequest) api.Response {
+	if err := validateTrack(request); err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		}
+	}
+
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	err := a.repo.UpdateTrack(ctx, trackFromRequest(trackID, request))
+	if err != nil {
+		return catalogError(err)
+	}
+
+	a.catalogChanged(model.SearchEntityTrack, trackID)
+
+	return nil
+}
+
+func (a *adminPanelUseCase) DeadLetters(pageNumber uint, pageSize uint) api.Response {
+	offset := (pageNumber - 1) * pageSize
+	limit := pageSize
+
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	count, letters, err := a.repo.GetDeadLettersAndCount(ctx, offset, limit)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db error",
+		}
+	}
+
+	return &api.DeadLettersResponse{
+		Code:        http.StatusOK,
+		DeadLetters: letters,
+		Count:       count,
+	}
+}
+
+// Republish produces only the letters it has claimed, the ones republished
+// already, e.g. by another admin, are skipped. A letter counts as republished
+// once the broker has confirmed it, the claim is released otherwise.
+func (a *adminPanelUseCase) Republish(ids []int) api.Response {
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	letters, err := a.repo.ClaimDeadLetters(ctx, ids)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db error",
+		}
+	}
+
+	if len(letters) == 0 {
+		return &api.ErrorResponse{
+			Code:  http.StatusNotFound,
+			Error: "no dead letters with such ids that haven't been republished yet",
+		}
+	}
+
+	republished := make([]int, 0, len(letters))
+	for _, letter := range letters {
+		err = a.producer.ProduceWithKeyAndWait(letter.Topic, letter.Key, []byte(letter.Payload))
+		if err != nil {
+			log.Printf("unable to republish dead letter %d: %s", letter.ID, err.Error())
+
+			err = a.repo.ReleaseDeadLetter(ctx, letter.ID)
+			if err != nil {
+				log.Printf("unable to release dead letter %d: %s", letter.ID, err.Error())
+			}
+			continue
+		}
+
+		republished = append(republished, letter.ID)
+	}
+
+	return &api.RepublishResponse{
+		Code:        http.StatusOK,
+		Republished: republished,
+	}
+}
+
+func (a *adminPanelUseCase) RefundOrder(orderID int) api.Response {
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	order, err := a.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		if err == domainRepository.ErrOrderNotFound {
+			return &api.ErrorResponse{
+				Code:  http.StatusNotFound,
+				Error: err.Error(),
+			}
+		}
+
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db error",
+		}
+	}
+
+	if !order.IsPaid || order.IsRefunded {
+		return &api.ErrorResponse{
+			Code:  http.StatusConflict,
+			Error: "only paid and not yet refunded orders can be refunded",
+		}
+	}
+
+	raw, err := json.Marshal(api.MoneyOperationKafkaMessage{
+		Type:    api.Refund,
+		UserID:  order.Orderer.ID,
+		OrderID: order.ID,
+		// an order can be refunded only once, so its id is a natural key
+		IdempotencyKey: fmt.Sprintf("refund-%d", order.ID),
+	})
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "unable to refund order",
+		}
+	}
+
+	err = a.producer.ProduceWithKey("money-operations", strconv.Itoa(order.Orderer.ID), raw)
+	if err != nil {
+		return utils.InterserviceCommunicationError()
+	}
+
+	return nil
+}
+
+func (a *adminPanelUseCase) ExchangeRates() api.Response {
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	rates, err := a.repo.GetExchangeRates(ctx)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db error",
+		}
+	}
+
+	return &api.ExchangeRatesResponse{
+		Code:  http.StatusOK,
+		Rates: rates,
+	}
+}
+
+func (a *adminPanelUseCase) SetExchangeRates(rates map[string]json.Number) api.Response {
+	result := make([]model.ExchangeRate, 0, len(rates))
+	for currency, rate := range rates {
+		if err := validateExchangeRate(currency, rate.String()); err != nil {
+			return &api.ErrorResponse{
+				Code:  http.StatusBadRequest,
+				Error: fmt.Sprintf("%s: %s", currency, err.Error()),
+			}
+		}
+
+		result = append(result, model.ExchangeRate{
+			Currency: currency,
+			Rate:     rate.String(),
+		})
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	err := a.repo.SetExchangeRates(ctx, result)
+	if err != nil {
+		log.Printf("unable to set exchange rates: %s", err.Error())
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db error",
+		}
+	}
+
+	return nil
+}
+
+func (a *adminPanelUseCase) DeleteExchangeRate(currency string) api.Response {
+	if currency == model.BaseCurrency {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "base currency cannot be deleted",
+		}
+	}
+
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	err := a.repo.DeleteExchangeRate(ctx, currency)
+	switch err {
+	case nil:
+		return nil
+	case domainRepository.ErrUnknownCurrency:
+		return &api.ErrorResponse{
+			Code:  http.StatusNotFound,
+			Error: err.Error(),
+		}
+	case domainRepository.ErrCurrencyInUse:
+		return &api.ErrorResponse{
+			Code:  http.StatusConflict,
+			Error: err.Error(),
+		}
+	default:
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db error",
+		}
+	}
+}
+
+// catalogChanged lets the search engine know that its suggestions are stale.
+// The change itself is already saved, so a failure is only logged.
+func (a *adminPanelUseCase) catalogChanged(entity string, id int) {
+	err := utils.ProduceCatalogMessage(api.CatalogKafkaMessage{
+		Entity: entity,
+		ID:     id,
+	}, a.producer)
+	if err != nil {
+		log.Printf("unable to produce catalog message: %s", err.Error())
+	}
+}
+
+func (a *adminPanelUseCase) ConsumeDeadLetters() {
+	a.consumer.ConsumeDeadLettersEternally(a.onDeadLetter, log.Printf, log.Printf)
+}
+
+func (a *adminPanelUseCase) onDeadLetter(letter kafka.DeadLetter) error {
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	return a.repo.AddDeadLetter(ctx, model.DeadLetter{
+		Topic:    letter.Topic,
+		Key:      string(letter.Key),
+		Payload:  string(letter.Value),
+		Error:    letter.Error,
+		Attempts: letter.Attempts,
+		FailedAt: letter.FailedAt,
+	})
+}
+
+func validateArtist(request api.ArtistRequest) error {
+	switch {
+	case len(request.Name) > 512:
+		return errors.New("artist name is too long")
+	case len(request.Genre) > 64:
+		return errors.New("genre is too long")
+	case len(request.ImageURL) > 128:
+		return errors.New("image url is too long")
+	}
+	return nil
+}
+
+func validateAlbum(request api.AlbumRequest) error {
+	price, err := model.ParseMoney(request.Price.String(), model.BaseCurrency)
+
+	switch {
+	case len(request.Name) > 512:
+		return errors.New("album name is too long")
+	case len(request.ImageURL) > 128:
+		return errors.New("image url is too long")
+	case err != nil:
+		return fmt.Errorf("invalid price: %s", err.Error())
+	case price.IsNegative():
+		return errors.New("price cannot be negative")
+	}
+
+	numbers := make(map[int]bool, len(request.Tracks))
+	for _, track := range request.Tracks {
+		if err := validateTrack(track); err != nil {
+			return err
+		}
+
+		if numbers[track.Number] {
+			return fmt.Errorf("track number %d is used more than once", track.Number)
+		}
+		numbers[track.Number] = true
+	}
+
+	return nil
+}
+
+func validateTrack(request api.TrackRequest) error {
+	switch {
+	case len(request.Name) > 512:
+		return errors.New("track name is too long")
+	case request.Number <= 0:
+		return errors.New("track number must be positive")
+	}
+	return nil
+}
+
+func artistFromRequest(id int, request api.ArtistRequest) model.Artist {
+	return model.Artist{
+		ID:       id,
+		Name:     request.Name,
+		Genre:    request.Genre,
+		ImageURL: request.ImageURL,
+	}
+}
+
+func validateExchangeRate(currency, rate string) error {
+	switch {
+	case !model.IsValidCurrency(currency):
+		return model.ErrInvalidCurrency
+	case currency == model.BaseCurrency:
+		return errors.New("rate of the base currency is always 1")
+	}
+
+	_, err := model.ParseRate(rate)
+	return err
+}
+
+// albumFromRequest expects the request to have passed validateAlbum.
+func albumFromRequest(id int, request api.AlbumRequest) model.Album {
+	price, _ := model.ParseMoney(request.Price.String(), model.BaseCurrency)
+
+	tracks := make([]model.Track, len(request.Tracks))
+	for i, track := range request.Tracks {
+		tracks[i] = trackFromRequest(0, track)
+	}
+
+	return model.Album{
+		ID:       id,
+		Name:     request.Name,
+		Author:   &model.Artist{ID: request.ArtistID},
+		ImageURL: request.ImageURL,
+		Price:    price,
+		Tracks:   tracks,
+	}
+}
+
+func trackFromRequest(id int, request api.TrackRequest) model.Track {
+	return model.Track{
+		ID:     id,
+		Name:   request.Name,
+		Number: request.Number,
+	}
+}
+
+func catalogError(err error) api.Response {
+	switch err {
+	case domainRepository.ErrArtistNotFound, domainRepository.ErrAlbumNotFound, domainRepository.ErrTrackNotFound:
+		return &api.ErrorResponse{
+			Code:  http.StatusNotFound,
+			Error: err.Error(),
+		}
+	case domainRepository.ErrTrackNumberTaken:
+		return &api.ErrorResponse{
+			Code:  http.StatusConflict,
+			Error: err.Error(),
+		}
+	default:
+		log.Printf("catalog update error: %s", err.Error())
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db error",
+		}
+	}
+}
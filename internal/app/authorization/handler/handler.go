@@ -13,8 +13,13 @@ import (
 type AuthorizationHandler interface {
 	HandleAuthentication(c *gin.Context)
 	HandleAuthorization(c *gin.Context)
+	HandleRefresh(c *gin.Context)
 	HandleLogout(c *gin.Context)
 	HandleRegistration(c *gin.Context)
+	HandleSessions(c *gin.Context)
+	HandleRevokeSession(c *gin.Context)
+	HandleRevokeAllSessions(c *gin.Context)
+	HandleRevokeUserSessions(c *gin.Context)
 }
 
 type authorizationHandler struct {
@@ -37,7 +42,7 @@ func (a *authorizationHandler) HandleAuthentication(c *gin.Context) {
 		return
 	}
 
-	utils.Send(c, a.useCase.Authenticate(authData[len("Basic "):]))
+	utils.Send(c, a.useCase.Authenticate(authData[len("Basic "):], c.GetHeader("User-Agent")))
 }
 
 func (a *authorizationHandler) HandleAuthorization(c *gin.Context) {
@@ -53,6 +58,20 @@ func (a *authorizationHandler) HandleAuthorization(c *gin.Context) {
 	utils.Send(c, a.useCase.Authorize(authData[len("Bearer "):]))
 }
 
+func (a *authorizationHandler) HandleRefresh(c *gin.Context) {
+	var request api.RefreshRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Send(c, &api.AuthenticationResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid request fields",
+		})
+		return
+	}
+
+	utils.Send(c, a.useCase.Refresh(request.RefreshToken))
+}
+
 func (a *authorizationHandler) HandleLogout(c *gin.Context) {
 	authData := c.GetHeader("Authorization")
 	if authData == "" || !strings.HasPrefix(authData, "Bearer ") {
@@ -91,3 +110,65 @@ func (a *authorizationHandler) HandleRegistration(c *gin.Context) {
 
 	c.String(http.StatusOK, "")
 }
+
+func (a *authorizationHandler) HandleSessions(c *gin.Context) {
+	jsonWebToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	utils.Send(c, a.useCase.Sessions(jsonWebToken))
+}
+
+func (a *authorizationHandler) HandleRevokeSession(c *gin.Context) {
+	jsonWebToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	sendOrOK(c, a.useCase.RevokeSession(jsonWebToken, c.Param("id")))
+}
+
+func (a *authorizationHandler) HandleRevokeAllSessions(c *gin.Context) {
+	jsonWebToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	sendOrOK(c, a.useCase.RevokeAllSessions(jsonWebToken))
+}
+
+func (a *authorizationHandler) HandleRevokeUserSessions(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid 'id' parameter",
+		})
+		return
+	}
+
+	sendOrOK(c, a.useCase.RevokeUserSessions(id))
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	authData := c.GetHeader("Authorization")
+	if authData == "" || !strings.HasPrefix(authData, "Bearer ") {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "bad authorization base64 token",
+		})
+		return "", false
+	}
+
+	return authData[len("Bearer "):], true
+}
+
+func sendOrOK(c *gin.Context, response api.Response) {
+	if response != nil {
+		utils.Send(c, response)
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/allnightmarel0Ng/albums/internal/domain/repository"
@@ -10,17 +13,39 @@ import (
 )
 
 var (
-	ErrUnexpected  = errors.New("unexpected error")
-	ErrJWTNotFound = errors.New("jwt not found")
+	ErrUnexpected           = errors.New("unexpected error")
+	ErrJWTNotFound          = errors.New("jwt not found")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrSessionNotFound      = errors.New("session not found")
 )
 
+const (
+	refreshTokenPrefix = "refresh:"
+	sessionPrefix      = "session:"
+	userSessionsPrefix = "sessions:"
+)
+
+type Session struct {
+	ID           string    `json:"id"`
+	UserID       int       `json:"userID"`
+	IsAdmin      bool      `json:"isAdmin"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UserAgent    string    `json:"userAgent"`
+	Jwt          string    `json:"jwt"`
+	RefreshToken string    `json:"refreshToken"`
+}
+
 type AuthorizationRepository interface {
 	GetIDPasswordHash(ctx context.Context, email string) (int, string, bool, error)
-	AddNewUser(ctx context.Context, email, password_hash string, isAdmin bool, nickname, imageURL string) error
+	AddNewUser(ctx context.Context, email, password_hash string, isAdmin bool, nickname, imageURL, currency string) error
 	FindUserByEmail(ctx context.Context, email string) (bool, error)
-	AddJWT(ctx context.Context, jwt string, expirationSeconds int) error
-	FindJWT(ctx context.Context, jwt string) error
+	SaveSession(ctx context.Context, session Session, jwtExpirationSeconds, refreshExpirationSeconds int) error
+	FindJWT(ctx context.Context, jwt string) (string, error)
 	DelJWT(ctx context.Context, jwt string) error
+	PopRefreshToken(ctx context.Context, token string) (string, error)
+	GetSession(ctx context.Context, sessionID string) (Session, error)
+	GetUserSessions(ctx context.Context, userID int) ([]Session, error)
+	DeleteSession(ctx context.Context, session Session) error
 }
 
 type authorizationRepository struct {
@@ -44,21 +69,42 @@ func (a *authorizationRepository) GetIDPasswordHash(ctx context.Context, email s
 	}
 }
 
-func (a *authorizationRepository) AddNewUser(ctx context.Context, email, password_hash string, isAdmin bool, nickname, imageURL string) error {
+func (a *authorizationRepository) AddNewUser(ctx context.Context, email, password_hash string, isAdmin bool, nickname, imageURL, currency string) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		return a.users.AddNewUser(ctx, email, password_hash, isAdmin, nickname, imageURL)
+		return a.users.AddNewUser(ctx, email, password_hash, isAdmin, nickname, imageURL, currency)
 	}
 }
 
-func (a *authorizationRepository) AddJWT(ctx context.Context, jwt string, expirationSeconds int) error {
+func (a *authorizationRepository) SaveSession(ctx context.Context, session Session, jwtExpirationSeconds, refreshExpirationSeconds int) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		err := a.redis.Set(ctx, jwt, "", time.Duration(expirationSeconds)*time.Second)
+		raw, err := json.Marshal(session)
+		if err != nil {
+			return ErrUnexpected
+		}
+
+		refreshExpiration := time.Duration(refreshExpirationSeconds) * time.Second
+		err = a.redis.Set(ctx, sessionPrefix+session.ID, raw, refreshExpiration)
+		if err != nil {
+			return ErrUnexpected
+		}
+
+		err = a.redis.SAdd(ctx, userSessionsKey(session.UserID), session.ID)
+		if err != nil {
+			return ErrUnexpected
+		}
+
+		err = a.redis.Set(ctx, session.Jwt, session.ID, time.Duration(jwtExpirationSeconds)*time.Second)
+		if err != nil {
+			return ErrUnexpected
+		}
+
+		err = a.redis.Set(ctx, refreshTokenPrefix+session.RefreshToken, session.ID, refreshExpiration)
 		if err != nil {
 			return ErrUnexpected
 		}
@@ -66,20 +112,20 @@ func (a *authorizationRepository) AddJWT(ctx context.Context, jwt string, expira
 	}
 }
 
-func (a *authorizationRepository) FindJWT(ctx context.Context, jwt string) error {
+func (a *authorizationRepository) FindJWT(ctx context.Context, jwt string) (string, error) {
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return "", ctx.Err()
 	default:
-		_, err := a.redis.Get(ctx, jwt)
+		sessionID, err := a.redis.Get(ctx, jwt)
 		if err != nil {
 			if err == redis.ErrNotFound {
-				return ErrJWTNotFound
+				return "", ErrJWTNotFound
 			} else {
-				return ErrUnexpected
+				return "", ErrUnexpected
 			}
 		}
-		return nil
+		return sessionID, nil
 	}
 }
 
@@ -100,6 +146,96 @@ func (a *authorizationRepository) DelJWT(ctx context.Context, jwt string) error
 	}
 }
 
+func (a *authorizationRepository) PopRefreshToken(ctx context.Context, token string) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+		sessionID, err := a.redis.GetDel(ctx, refreshTokenPrefix+token)
+		if err != nil {
+			if err == redis.ErrNotFound {
+				return "", ErrRefreshTokenNotFound
+			} else {
+				return "", ErrUnexpected
+			}
+		}
+		return sessionID, nil
+	}
+}
+
+func (a *authorizationRepository) GetSession(ctx context.Context, sessionID string) (Session, error) {
+	select {
+	case <-ctx.Done():
+		return Session{}, ctx.Err()
+	default:
+		raw, err := a.redis.Get(ctx, sessionPrefix+sessionID)
+		if err != nil {
+			if err == redis.ErrNotFound {
+				return Session{}, ErrSessionNotFound
+			} else {
+				return Session{}, ErrUnexpected
+			}
+		}
+
+		var session Session
+		err = json.Unmarshal([]byte(raw), &session)
+		if err != nil {
+			return Session{}, ErrUnexpected
+		}
+		return session, nil
+	}
+}
+
+func (a *authorizationRepository) GetUserSessions(ctx context.Context, userID int) ([]Session, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		ids, err := a.redis.SMembers(ctx, userSessionsKey(userID))
+		if err != nil {
+			return nil, ErrUnexpected
+		}
+
+		result := make([]Session, 0, len(ids))
+		for _, id := range ids {
+			session, err := a.GetSession(ctx, id)
+			if err == ErrSessionNotFound {
+				// the session has expired, so the index entry is stale
+				a.redis.SRem(ctx, userSessionsKey(userID), id)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, session)
+		}
+
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		})
+		return result, nil
+	}
+}
+
+func (a *authorizationRepository) DeleteSession(ctx context.Context, session Session) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		err := a.redis.Del(ctx, session.Jwt, refreshTokenPrefix+session.RefreshToken, sessionPrefix+session.ID)
+		if err != nil {
+			return ErrUnexpected
+		}
+
+		err = a.redis.SRem(ctx, userSessionsKey(session.UserID), session.ID)
+		if err != nil {
+			return ErrUnexpected
+		}
+		return nil
+	}
+}
+
 func (a *authorizationRepository) FindUserByEmail(ctx context.Context, email string) (bool, error) {
 	select {
 	case <-ctx.Done():
@@ -108,3 +244,7 @@ func (a *authorizationRepository) FindUserByEmail(ctx context.Context, email str
 		return a.users.FindUserByEmail(ctx, email)
 	}
 }
+
+func userSessionsKey(userID int) string {
+	return fmt.Sprintf("%s%d", userSessionsPrefix, userID)
+}
@@ -2,22 +2,36 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/allnightmarel0Ng/albums/internal/app/authorization/repository"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	domainRepository "github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	accessTokenLifetime  = time.Hour
+	refreshTokenLifetime = 30 * 24 * time.Hour
+)
+
 type AuthorizationUseCase interface {
-	Authenticate(b64 string) api.Response
+	Authenticate(b64, userAgent string) api.Response
 	Authorize(jsonWebToken string) api.Response
+	Refresh(refreshToken string) api.Response
 	Logout(jsonWebToken string) api.Response
 	Register(request api.RegistrationRequest) api.Response
+	Sessions(jsonWebToken string) api.Response
+	RevokeSession(jsonWebToken, sessionID string) api.Response
+	RevokeAllSessions(jsonWebToken string) api.Response
+	RevokeUserSessions(userID int) api.Response
 }
 
 type authorizationUseCase struct {
@@ -32,7 +46,7 @@ func NewAuthorizationUseCase(repo repository.AuthorizationRepository, jwtSecretK
 	}
 }
 
-func (a *authorizationUseCase) Authenticate(b64 string) api.Response {
+func (a *authorizationUseCase) Authenticate(b64, userAgent string) api.Response {
 	rawCredentials, err := base64.StdEncoding.DecodeString(b64)
 	if err != nil {
 		return &api.AuthenticationResponse{
@@ -60,39 +74,28 @@ func (a *authorizationUseCase) Authenticate(b64 string) api.Response {
 		}
 	}
 
-	result, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":      id,
-		"isAdmin": isAdmin,
-	}).SignedString(a.jwtSecretKey)
-	if err != nil {
-		return &api.AuthenticationResponse{
-			Code:  http.StatusUnauthorized,
-			Error: "unable to create jwt key",
-		}
-	}
-
-	ctx, cancel = utils.DeadlineContext(5)
-	defer cancel()
-	err = a.repo.AddJWT(ctx, result, 3600)
+	sessionID, err := newRandomToken(16)
 	if err != nil {
 		return &api.AuthenticationResponse{
 			Code:  http.StatusInternalServerError,
-			Error: "unable to create jwt key",
+			Error: "unable to create session",
 		}
 	}
 
-	return &api.AuthenticationResponse{
-		Code:    http.StatusOK,
-		Jwt:     result,
-		IsAdmin: &isAdmin,
-	}
+	return a.issueTokens(repository.Session{
+		ID:        sessionID,
+		UserID:    id,
+		IsAdmin:   isAdmin,
+		CreatedAt: time.Now(),
+		UserAgent: userAgent,
+	})
 }
 
 func (a *authorizationUseCase) Authorize(jsonWebToken string) api.Response {
 	ctx, cancel := utils.DeadlineContext(5)
 	defer cancel()
 
-	err := a.repo.FindJWT(ctx, jsonWebToken)
+	_, err := a.repo.FindJWT(ctx, jsonWebToken)
 	if err != nil {
 		switch err {
 		case context.DeadlineExceeded:
@@ -112,24 +115,132 @@ func (a *authorizationUseCase) Authorize(jsonWebToken string) api.Response {
 	return utils.GetJWTClaims(jsonWebToken, string(a.jwtSecretKey))
 }
 
+func (a *authorizationUseCase) Refresh(refreshToken string) api.Response {
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	sessionID, err := a.repo.PopRefreshToken(ctx, refreshToken)
+	if err == nil {
+		var session repository.Session
+		session, err = a.repo.GetSession(ctx, sessionID)
+		if err == nil {
+			a.repo.DelJWT(ctx, session.Jwt)
+			return a.issueTokens(session)
+		}
+	}
+
+	switch err {
+	case repository.ErrRefreshTokenNotFound, repository.ErrSessionNotFound:
+		return &api.AuthenticationResponse{
+			Code:  http.StatusUnauthorized,
+			Error: err.Error(),
+		}
+	default:
+		return &api.AuthenticationResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "jwt storage error",
+		}
+	}
+}
+
 func (a *authorizationUseCase) Logout(jsonWebToken string) api.Response {
 	ctx, cancel := utils.DeadlineContext(5)
 	defer cancel()
 
-	err := a.repo.DelJWT(ctx, jsonWebToken)
+	sessionID, err := a.repo.FindJWT(ctx, jsonWebToken)
+	if err == nil {
+		var session repository.Session
+		session, err = a.repo.GetSession(ctx, sessionID)
+		if err == nil {
+			err = a.repo.DeleteSession(ctx, session)
+		} else if err == repository.ErrSessionNotFound {
+			err = a.repo.DelJWT(ctx, jsonWebToken)
+		}
+	}
+
 	if err != nil {
-		switch err {
-		case context.DeadlineExceeded:
-		case repository.ErrUnexpected:
-			return &api.ErrorResponse{
-				Code:  http.StatusInternalServerError,
-				Error: "jwt storage error",
-			}
-		default:
-			return &api.ErrorResponse{
-				Code:  http.StatusUnauthorized,
-				Error: err.Error(),
-			}
+		return sessionErrorResponse(err)
+	}
+
+	return nil
+}
+
+func (a *authorizationUseCase) Sessions(jsonWebToken string) api.Response {
+	claims, currentSessionID, response := a.currentSession(jsonWebToken)
+	if response != nil {
+		return response
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	sessions, err := a.repo.GetUserSessions(ctx, claims.ID)
+	if err != nil {
+		return sessionErrorResponse(err)
+	}
+
+	result := make([]model.Session, len(sessions))
+	for i, session := range sessions {
+		result[i] = model.Session{
+			ID:        session.ID,
+			CreatedAt: session.CreatedAt,
+			UserAgent: session.UserAgent,
+			Current:   session.ID == currentSessionID,
+		}
+	}
+
+	return &api.SessionsResponse{
+		Code:     http.StatusOK,
+		Sessions: result,
+	}
+}
+
+func (a *authorizationUseCase) RevokeSession(jsonWebToken, sessionID string) api.Response {
+	claims, _, response := a.currentSession(jsonWebToken)
+	if response != nil {
+		return response
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	session, err := a.repo.GetSession(ctx, sessionID)
+	if err == nil && session.UserID != claims.ID {
+		err = repository.ErrSessionNotFound
+	}
+	if err == nil {
+		err = a.repo.DeleteSession(ctx, session)
+	}
+
+	if err != nil {
+		return sessionErrorResponse(err)
+	}
+
+	return nil
+}
+
+func (a *authorizationUseCase) RevokeAllSessions(jsonWebToken string) api.Response {
+	claims, _, response := a.currentSession(jsonWebToken)
+	if response != nil {
+		return response
+	}
+
+	return a.RevokeUserSessions(claims.ID)
+}
+
+func (a *authorizationUseCase) RevokeUserSessions(userID int) api.Response {
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	sessions, err := a.repo.GetUserSessions(ctx, userID)
+	if err != nil {
+		return sessionErrorResponse(err)
+	}
+
+	for _, session := range sessions {
+		err = a.repo.DeleteSession(ctx, session)
+		if err != nil {
+			return sessionErrorResponse(err)
 		}
 	}
 
@@ -170,7 +281,18 @@ func (a *authorizationUseCase) Register(request api.RegistrationRequest) api.Res
 		}
 	}
 
-	err = a.repo.AddNewUser(ctx, request.Email, string(hashed), *request.IsAdmin, request.Nickname, request.ImageURL)
+	currency := request.Currency
+	if currency == "" {
+		currency = model.BaseCurrency
+	}
+
+	err = a.repo.AddNewUser(ctx, request.Email, string(hashed), *request.IsAdmin, request.Nickname, request.ImageURL, currency)
+	if err == domainRepository.ErrUnknownCurrency {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "no exchange rate for such currency",
+		}
+	}
 	if err != nil {
 		return &api.ErrorResponse{
 			Code:  http.StatusInternalServerError,
@@ -180,3 +302,96 @@ func (a *authorizationUseCase) Register(request api.RegistrationRequest) api.Res
 
 	return nil
 }
+
+func (a *authorizationUseCase) issueTokens(session repository.Session) api.Response {
+	now := time.Now()
+	result, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":      session.UserID,
+		"isAdmin": session.IsAdmin,
+		"iat":     now.Unix(),
+		"exp":     now.Add(accessTokenLifetime).Unix(),
+	}).SignedString(a.jwtSecretKey)
+	if err != nil {
+		return &api.AuthenticationResponse{
+			Code:  http.StatusUnauthorized,
+			Error: "unable to create jwt key",
+		}
+	}
+
+	refreshToken, err := newRandomToken(32)
+	if err != nil {
+		return &api.AuthenticationResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "unable to create refresh token",
+		}
+	}
+
+	session.Jwt = result
+	session.RefreshToken = refreshToken
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	err = a.repo.SaveSession(ctx, session, int(accessTokenLifetime.Seconds()), int(refreshTokenLifetime.Seconds()))
+	if err != nil {
+		return &api.AuthenticationResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "unable to create jwt key",
+		}
+	}
+
+	return &api.AuthenticationResponse{
+		Code:         http.StatusOK,
+		Jwt:          result,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenLifetime.Seconds()),
+		IsAdmin:      &session.IsAdmin,
+	}
+}
+
+func (a *authorizationUseCase) currentSession(jsonWebToken string) (*api.AuthorizationResponse, string, api.Response) {
+	response := a.Authorize(jsonWebToken)
+	if response.GetCode() != http.StatusOK {
+		return nil, "", response
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	sessionID, err := a.repo.FindJWT(ctx, jsonWebToken)
+	if err != nil {
+		return nil, "", sessionErrorResponse(err)
+	}
+
+	return response.(*api.AuthorizationResponse), sessionID, nil
+}
+
+func sessionErrorResponse(err error) api.Response {
+	switch err {
+	case repository.ErrSessionNotFound:
+		return &api.ErrorResponse{
+			Code:  http.StatusNotFound,
+			Error: err.Error(),
+		}
+	case repository.ErrJWTNotFound:
+		return &api.ErrorResponse{
+			Code:  http.StatusUnauthorized,
+			Error: err.Error(),
+		}
+	default:
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "jwt storage error",
+		}
+	}
+}
+
+func newRandomToken(size int) (string, error) {
+	raw := make([]byte, size)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -10,33 +10,69 @@ import (
 
 	"github.com/allnightmarel0Ng/albums/internal/app/gateway/usecase"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
 type GatewayHandler interface {
 	HandleLogin(c *gin.Context)
+	HandleRefresh(c *gin.Context)
 	HandleLogout(c *gin.Context)
 	HandleRegistration(c *gin.Context)
+	HandleSessions(c *gin.Context)
+	HandleRevokeSession(c *gin.Context)
+	HandleRevokeAllSessions(c *gin.Context)
 
 	HandleMainPage(c *gin.Context)
 	HandleSearch(c *gin.Context)
+	HandleSuggest(c *gin.Context)
+	HandleChart(c *gin.Context)
+	HandleArtistChartPositions(c *gin.Context)
 
 	HandleUserProfile(c *gin.Context)
+	HandleTransactions(c *gin.Context)
+	HandleChangeCurrency(c *gin.Context)
+	HandleExchangeRates(c *gin.Context)
 	HandleArtistProfile(c *gin.Context)
 	HandleAlbumProfile(c *gin.Context)
 
 	HandleOrderAdd(c *gin.Context)
 	HandleOrderRemove(c *gin.Context)
 	HandleOrders(c *gin.Context)
+	HandleCancelOrder(c *gin.Context)
+	HandleWishlistAdd(c *gin.Context)
+	HandleWishlistRemove(c *gin.Context)
+	HandleWishlist(c *gin.Context)
+	HandleMoveWishlist(c *gin.Context)
 
 	HandleDeposit(c *gin.Context)
 	HandleBuy(c *gin.Context)
+	HandleOperation(c *gin.Context)
+
+	HandleNotifications(c *gin.Context)
+	HandleMarkNotificationsRead(c *gin.Context)
 
 	HandleLogs(c *gin.Context)
 	HandleDelete(c *gin.Context)
 	HandleSaveDump(c *gin.Context)
 	HandleLoadDump(c *gin.Context)
+
+	HandleAddArtist(c *gin.Context)
+	HandleUpdateArtist(c *gin.Context)
+	HandleAddAlbum(c *gin.Context)
+	HandleUpdateAlbum(c *gin.Context)
+	HandleAddTrack(c *gin.Context)
+	HandleUpdateTrack(c *gin.Context)
+	HandleRevokeUserSessions(c *gin.Context)
+	HandleDeadLetters(c *gin.Context)
+	HandleRepublish(c *gin.Context)
+	HandleRefundOrder(c *gin.Context)
+	HandleAdminExchangeRates(c *gin.Context)
+	HandleLoadExchangeRates(c *gin.Context)
+	HandleSetExchangeRate(c *gin.Context)
+	HandleDeleteExchangeRate(c *gin.Context)
 }
 
 type gatewayHandler struct {
@@ -50,7 +86,12 @@ func NewGatewayHandler(useCase usecase.GatewayUseCase) GatewayHandler {
 }
 
 func (g *gatewayHandler) HandleLogin(c *gin.Context) {
-	code, raw := g.useCase.Authentication(c.GetHeader("Authorization"))
+	code, raw := g.useCase.Authentication(c.GetHeader("Authorization"), c.GetHeader("User-Agent"))
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleRefresh(c *gin.Context) {
+	code, raw := g.useCase.Refresh(c.Request.Body)
 	utils.SendRaw(c, code, raw)
 }
 
@@ -64,21 +105,75 @@ func (g *gatewayHandler) HandleRegistration(c *gin.Context) {
 	utils.SendRaw(c, code, raw)
 }
 
+func (g *gatewayHandler) HandleSessions(c *gin.Context) {
+	code, raw := g.useCase.Sessions(c.GetHeader("Authorization"))
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleRevokeSession(c *gin.Context) {
+	code, raw := g.useCase.RevokeSession(c.GetHeader("Authorization"), c.Param("id"))
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleRevokeAllSessions(c *gin.Context) {
+	code, raw := g.useCase.RevokeAllSessions(c.GetHeader("Authorization"))
+	utils.SendRaw(c, code, raw)
+}
+
 func (g *gatewayHandler) HandleMainPage(c *gin.Context) {
-	code, raw := g.useCase.MainPage(c.Request.Body)
+	code, raw := g.useCase.MainPage(c.GetHeader("Authorization"), c.Request.Body)
 	utils.SendRaw(c, code, raw)
 }
 
 func (g *gatewayHandler) HandleSearch(c *gin.Context) {
-	code, raw := g.useCase.Search(c.Request.Body)
+	var request api.SearchRequest
+
+	if err := c.ShouldBind(&request); err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid search request",
+		})
+		return
+	}
+
+	code, raw := g.useCase.Search(c.GetHeader("Authorization"), request)
 	utils.SendRaw(c, code, raw)
 }
 
+func (g *gatewayHandler) HandleSuggest(c *gin.Context) {
+	code, raw := g.useCase.Suggest(c.Request.URL.RawQuery)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleChart(c *gin.Context) {
+	code, raw := g.useCase.Chart(c.Request.URL.RawQuery)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleArtistChartPositions(c *gin.Context) {
+	handleProfiles(c, g.useCase.ArtistChartPositions)
+}
+
 func (g *gatewayHandler) HandleUserProfile(c *gin.Context) {
 	code, raw := g.useCase.UserProfile(c.GetHeader("Authorization"))
 	utils.SendRaw(c, code, raw)
 }
 
+func (g *gatewayHandler) HandleTransactions(c *gin.Context) {
+	code, raw := g.useCase.Transactions(c.GetHeader("Authorization"), c.Request.URL.RawQuery)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleChangeCurrency(c *gin.Context) {
+	code, raw := g.useCase.ChangeCurrency(c.GetHeader("Authorization"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleExchangeRates(c *gin.Context) {
+	code, raw := g.useCase.ExchangeRates()
+	utils.SendRaw(c, code, raw)
+}
+
 func (g *gatewayHandler) HandleArtistProfile(c *gin.Context) {
 	handleProfiles(c, g.useCase.ArtistProfile)
 }
@@ -100,6 +195,29 @@ func (g *gatewayHandler) HandleOrders(c *gin.Context) {
 	utils.SendRaw(c, code, raw)
 }
 
+func (g *gatewayHandler) HandleCancelOrder(c *gin.Context) {
+	code, raw := g.useCase.CancelOrder(c.GetHeader("Authorization"))
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleWishlistAdd(c *gin.Context) {
+	handleOrderAction(c, g.useCase.AddToWishlist)
+}
+
+func (g *gatewayHandler) HandleWishlistRemove(c *gin.Context) {
+	handleOrderAction(c, g.useCase.RemoveFromWishlist)
+}
+
+func (g *gatewayHandler) HandleWishlist(c *gin.Context) {
+	code, raw := g.useCase.Wishlist(c.GetHeader("Authorization"))
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleMoveWishlist(c *gin.Context) {
+	code, raw := g.useCase.MoveWishlistToOrder(c.GetHeader("Authorization"))
+	utils.SendRaw(c, code, raw)
+}
+
 func (g *gatewayHandler) HandleDeposit(c *gin.Context) {
 	var request api.DepositRequest
 
@@ -111,33 +229,71 @@ func (g *gatewayHandler) HandleDeposit(c *gin.Context) {
 		return
 	}
 
-	response := g.useCase.Deposit(c.GetHeader("Authorization"), request.Money)
-	if response != nil {
-		utils.Send(c, response)
+	money, err := model.ParseMoney(request.Money.String(), request.Currency)
+	if err == nil && money.Amount <= 0 {
+		err = model.ErrInvalidAmount
+	}
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		})
 		return
 	}
 
-	c.String(http.StatusOK, "")
+	idempotencyKey, ok := getIdempotencyKey(c)
+	if !ok {
+		return
+	}
+
+	utils.Send(c, g.useCase.Deposit(c.GetHeader("Authorization"), money, idempotencyKey))
 }
 
 func (g *gatewayHandler) HandleBuy(c *gin.Context) {
-	response := g.useCase.Buy(c.GetHeader("Authorization"))
-	if response != nil {
-		utils.Send(c, response)
+	idempotencyKey, ok := getIdempotencyKey(c)
+	if !ok {
 		return
 	}
 
-	c.String(http.StatusOK, "")
+	utils.Send(c, g.useCase.Buy(c.GetHeader("Authorization"), idempotencyKey))
 }
 
-func (g *gatewayHandler) HandleLogs(c *gin.Context) {
-	params := c.Param("pageNumber")
-	query := c.Request.URL.RawQuery
-	if query != "" {
-		params += "?" + query
+// maxOperationWait bounds the wait query parameter of /operations/:id, so that
+// a client can't hold a connection open for long.
+const maxOperationWait = 30 * time.Second
+
+func (g *gatewayHandler) HandleOperation(c *gin.Context) {
+	var wait time.Duration
+	if raw := c.Query("wait"); raw != "" {
+		var err error
+		wait, err = time.ParseDuration(raw)
+		if err != nil || wait < 0 {
+			utils.Send(c, &api.ErrorResponse{
+				Code:  http.StatusBadRequest,
+				Error: "invalid wait duration",
+			})
+			return
+		}
+	}
+	if wait > maxOperationWait {
+		wait = maxOperationWait
 	}
 
-	code, raw := g.useCase.Logs(c.GetHeader("Authorization"), params)
+	utils.Send(c, g.useCase.Operation(c.GetHeader("Authorization"), c.Param("id"), wait))
+}
+
+func (g *gatewayHandler) HandleNotifications(c *gin.Context) {
+	code, raw := g.useCase.Notifications(c.GetHeader("Authorization"), c.GetHeader("Accept-Language"), c.Request.URL.RawQuery)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleMarkNotificationsRead(c *gin.Context) {
+	code, raw := g.useCase.MarkNotificationsRead(c.GetHeader("Authorization"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleLogs(c *gin.Context) {
+	code, raw := g.useCase.Logs(c.GetHeader("Authorization"), pageParams(c))
 	utils.SendRaw(c, code, raw)
 }
 
@@ -206,6 +362,96 @@ func (g *gatewayHandler) HandleLoadDump(c *gin.Context) {
 	c.String(http.StatusOK, "")
 }
 
+func (g *gatewayHandler) HandleAddArtist(c *gin.Context) {
+	code, raw := g.useCase.AddArtist(c.GetHeader("Authorization"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleUpdateArtist(c *gin.Context) {
+	code, raw := g.useCase.UpdateArtist(c.GetHeader("Authorization"), c.Param("id"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleAddAlbum(c *gin.Context) {
+	code, raw := g.useCase.AddAlbum(c.GetHeader("Authorization"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleUpdateAlbum(c *gin.Context) {
+	code, raw := g.useCase.UpdateAlbum(c.GetHeader("Authorization"), c.Param("id"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleAddTrack(c *gin.Context) {
+	code, raw := g.useCase.AddTrack(c.GetHeader("Authorization"), c.Param("id"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleUpdateTrack(c *gin.Context) {
+	code, raw := g.useCase.UpdateTrack(c.GetHeader("Authorization"), c.Param("id"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleRevokeUserSessions(c *gin.Context) {
+	code, raw := g.useCase.RevokeUserSessions(c.GetHeader("Authorization"), c.Param("id"))
+	utils.SendRaw(c, code, raw)
+}
+
+// getIdempotencyKey reads the client supplied key or generates a fresh one, so
+// retried requests with the same key are applied only once.
+func getIdempotencyKey(c *gin.Context) (string, bool) {
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		key = kafka.NewMessageKey()
+	}
+
+	if len(key) > 128 {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "idempotency key is too long",
+		})
+		return "", false
+	}
+
+	c.Header("Idempotency-Key", key)
+	return key, true
+}
+
+func (g *gatewayHandler) HandleDeadLetters(c *gin.Context) {
+	code, raw := g.useCase.DeadLetters(c.GetHeader("Authorization"), pageParams(c))
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleRepublish(c *gin.Context) {
+	code, raw := g.useCase.Republish(c.GetHeader("Authorization"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleRefundOrder(c *gin.Context) {
+	code, raw := g.useCase.RefundOrder(c.GetHeader("Authorization"), c.Param("id"))
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleAdminExchangeRates(c *gin.Context) {
+	code, raw := g.useCase.AdminExchangeRates(c.GetHeader("Authorization"))
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleLoadExchangeRates(c *gin.Context) {
+	code, raw := g.useCase.LoadExchangeRates(c.GetHeader("Authorization"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleSetExchangeRate(c *gin.Context) {
+	code, raw := g.useCase.SetExchangeRate(c.GetHeader("Authorization"), c.Param("currency"), c.Request.Body)
+	utils.SendRaw(c, code, raw)
+}
+
+func (g *gatewayHandler) HandleDeleteExchangeRate(c *gin.Context) {
+	code, raw := g.useCase.DeleteExchangeRate(c.GetHeader("Authorization"), c.Param("currency"))
+	utils.SendRaw(c, code, raw)
+}
+
 func handleOrderAction(c *gin.Context, callback func(string, int) (int, []byte)) {
 	id, err := utils.GetParam(c, "id")
 	if err != nil {
@@ -225,3 +471,13 @@ func handleProfiles(c *gin.Context, callback func(string) (int, []byte)) {
 	code, raw := callback(id)
 	utils.SendRaw(c, code, raw)
 }
+
+func pageParams(c *gin.Context) string {
+	params := c.Param("pageNumber")
+	query := c.Request.URL.RawQuery
+	if query != "" {
+		params += "?" + query
+	}
+
+	return params
+}
@@ -2,52 +2,99 @@ package usecase
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"strconv"
+	"time"
 
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
 )
 
 type GatewayUseCase interface {
-	Authentication(authHeader string) (int, []byte)
+	Authentication(authHeader, userAgent string) (int, []byte)
+	Refresh(body io.Reader) (int, []byte)
 	Logout(authHeader string) (int, []byte)
+	Sessions(authHeader string) (int, []byte)
+	RevokeSession(authHeader string, params string) (int, []byte)
+	RevokeAllSessions(authHeader string) (int, []byte)
 	Register(body io.Reader) (int, []byte)
 
-	MainPage(body io.Reader) (int, []byte)
-	Search(body io.Reader) (int, []byte)
+	MainPage(authHeader string, body io.Reader) (int, []byte)
+	Search(authHeader string, request api.SearchRequest) (int, []byte)
+	Suggest(query string) (int, []byte)
+	Chart(query string) (int, []byte)
+	ArtistChartPositions(params string) (int, []byte)
 
 	UserProfile(jsonWebToken string) (int, []byte)
+	Transactions(authHeader string, query string) (int, []byte)
+	ChangeCurrency(authHeader string, body io.Reader) (int, []byte)
+	ExchangeRates() (int, []byte)
 	ArtistProfile(params string) (int, []byte)
 	AlbumProfile(params string) (int, []byte)
 
 	AddToOrder(authHeader string, albumID int) (int, []byte)
 	RemoveFromOrder(authHeader string, albumID int) (int, []byte)
 	UserOrders(jsonWebToken string) (int, []byte)
+	CancelOrder(authHeader string) (int, []byte)
+	AddToWishlist(authHeader string, albumID int) (int, []byte)
+	RemoveFromWishlist(authHeader string, albumID int) (int, []byte)
+	Wishlist(authHeader string) (int, []byte)
+	MoveWishlistToOrder(authHeader string) (int, []byte)
 
-	Deposit(authHeader string, diff uint) api.Response
-	Buy(authHeader string) api.Response
+	Deposit(authHeader string, diff model.Money, idempotencyKey string) api.Response
+	Buy(authHeader string, idempotencyKey string) api.Response
+	Operation(authHeader string, id string, wait time.Duration) api.Response
+
+	Notifications(authHeader, acceptLanguage, query string) (int, []byte)
+	MarkNotificationsRead(authHeader string, body io.Reader) (int, []byte)
 
 	Logs(authHeader string, params string) (int, []byte)
 	DeleteAlbum(authHeader string, params string) (int, []byte)
 	SaveDump(authHeader string) (int, []byte)
 	LoadDump(authHeader, filePath string) (int, []byte)
 	AuthorizeAdmin(authHeader string) (int, []byte)
+
+	AddArtist(authHeader string, body io.Reader) (int, []byte)
+	UpdateArtist(authHeader string, params string, body io.Reader) (int, []byte)
+	AddAlbum(authHeader string, body io.Reader) (int, []byte)
+	UpdateAlbum(authHeader string, params string, body io.Reader) (int, []byte)
+	AddTrack(authHeader string, params string, body io.Reader) (int, []byte)
+	UpdateTrack(authHeader string, params string, body io.Reader) (int, []byte)
+	RevokeUserSessions(authHeader string, params string) (int, []byte)
+	DeadLetters(authHeader string, params string) (int, []byte)
+	Republish(authHeader string, body io.Reader) (int, []byte)
+	RefundOrder(authHeader string, params string) (int, []byte)
+	AdminExchangeRates(authHeader string) (int, []byte)
+	LoadExchangeRates(authHeader string, body io.Reader) (int, []byte)
+	SetExchangeRate(authHeader string, params string, body io.Reader) (int, []byte)
+	DeleteExchangeRate(authHeader string, params string) (int, []byte)
 }
 
+// operationPollInterval is how often Operation rereads a pending operation
+// while the client is waiting for it to finish.
+const operationPollInterval = 200 * time.Millisecond
+
 type gatewayUseCase struct {
-	producer *kafka.Producer
+	producer   *kafka.Producer
+	operations repository.OperationRepository
 
 	authorizationPort   string
 	profilePort         string
 	orderManagementPort string
 	searchEnginePort    string
 	adminPanelPort      string
+	notificationsPort   string
 
 	jwtSecretKey string
 
@@ -59,11 +106,13 @@ type gatewayUseCase struct {
 
 func NewGatewayUseCase(
 	producer *kafka.Producer,
+	operations repository.OperationRepository,
 	authorizationPort,
 	profilePort,
 	orderManagementPort,
 	searchEnginePort,
 	adminPanelPort,
+	notificationsPort,
 	jwtSecretKey,
 	postgresUser,
 	postgresPassword,
@@ -71,11 +120,13 @@ func NewGatewayUseCase(
 	postgresDB string) GatewayUseCase {
 	return &gatewayUseCase{
 		producer:            producer,
+		operations:          operations,
 		authorizationPort:   authorizationPort,
 		profilePort:         profilePort,
 		orderManagementPort: orderManagementPort,
 		searchEnginePort:    searchEnginePort,
 		adminPanelPort:      adminPanelPort,
+		notificationsPort:   notificationsPort,
 		jwtSecretKey:        jwtSecretKey,
 
 		postgresUser:     postgresUser,
@@ -85,14 +136,33 @@ func NewGatewayUseCase(
 	}
 }
 
-func (g *gatewayUseCase) Authentication(authHeader string) (int, []byte) {
-	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://authorization:%s/authenticate", g.authorizationPort), authHeader, nil)
+func (g *gatewayUseCase) Authentication(authHeader, userAgent string) (int, []byte) {
+	return utils.RequestWithHeadersAndParseResponse("GET", fmt.Sprintf("http://authorization:%s/authenticate", g.authorizationPort), map[string]string{
+		"Authorization": authHeader,
+		"User-Agent":    userAgent,
+	}, nil)
+}
+
+func (g *gatewayUseCase) Refresh(body io.Reader) (int, []byte) {
+	return utils.RequestAndParseResponse("POST", fmt.Sprintf("http://authorization:%s/refresh", g.authorizationPort), "", body)
 }
 
 func (g *gatewayUseCase) Logout(authHeader string) (int, []byte) {
 	return utils.RequestAndParseResponse("POST", fmt.Sprintf("http://authorization:%s/logout", g.authorizationPort), authHeader, nil)
 }
 
+func (g *gatewayUseCase) Sessions(authHeader string) (int, []byte) {
+	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://authorization:%s/sessions", g.authorizationPort), authHeader, nil)
+}
+
+func (g *gatewayUseCase) RevokeSession(authHeader string, params string) (int, []byte) {
+	return utils.RequestAndParseResponse("DELETE", fmt.Sprintf("http://authorization:%s/sessions/%s", g.authorizationPort, params), authHeader, nil)
+}
+
+func (g *gatewayUseCase) RevokeAllSessions(authHeader string) (int, []byte) {
+	return utils.RequestAndParseResponse("DELETE", fmt.Sprintf("http://authorization:%s/sessions", g.authorizationPort), authHeader, nil)
+}
+
 func (g *gatewayUseCase) Register(body io.Reader) (int, []byte) {
 	return utils.RequestAndParseResponse("POST", fmt.Sprintf("http://authorization:%s/registration", g.authorizationPort), "", body)
 }
@@ -109,6 +179,39 @@ func (g *gatewayUseCase) UserProfile(authHeader string) (int, []byte) {
 	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://profile:%s/users/%d", g.profilePort, claims.ID), "", nil)
 }
 
+func (g *gatewayUseCase) Transactions(authHeader string, query string) (int, []byte) {
+	authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+	if authorizationResponse.GetCode() != http.StatusOK {
+		raw, _ := json.Marshal(authorizationResponse)
+		return authorizationResponse.GetCode(), raw
+	}
+
+	claims := authorizationResponse.(*api.AuthorizationResponse)
+
+	url := fmt.Sprintf("http://profile:%s/users/%d/transactions", g.profilePort, claims.ID)
+	if query != "" {
+		url += "?" + query
+	}
+
+	return utils.RequestAndParseResponse("GET", url, "", nil)
+}
+
+func (g *gatewayUseCase) ChangeCurrency(authHeader string, body io.Reader) (int, []byte) {
+	authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+	if authorizationResponse.GetCode() != http.StatusOK {
+		raw, _ := json.Marshal(authorizationResponse)
+		return authorizationResponse.GetCode(), raw
+	}
+
+	claims := authorizationResponse.(*api.AuthorizationResponse)
+
+	return utils.RequestAndParseResponse("PUT", fmt.Sprintf("http://profile:%s/users/%d/currency", g.profilePort, claims.ID), "", body)
+}
+
+func (g *gatewayUseCase) ExchangeRates() (int, []byte) {
+	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://profile:%s/rates", g.profilePort), "", nil)
+}
+
 func (g *gatewayUseCase) ArtistProfile(params string) (int, []byte) {
 	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://profile:%s/artists/%s", g.profilePort, params), "", nil)
 }
@@ -125,7 +228,7 @@ func (g *gatewayUseCase) RemoveFromOrder(authHeader string, albumID int) (int, [
 	return g.orderAction(albumID, authHeader, "remove")
 }
 
-func (g *gatewayUseCase) Deposit(authHeader string, diff uint) api.Response {
+func (g *gatewayUseCase) Deposit(authHeader string, diff model.Money, idempotencyKey string) api.Response {
 	authResponse := utils.Authorize(authHeader, g.authorizationPort)
 	if authResponse.GetCode() != http.StatusOK {
 		return authResponse
@@ -133,29 +236,19 @@ func (g *gatewayUseCase) Deposit(authHeader string, diff uint) api.Response {
 
 	claims := authResponse.(*api.AuthorizationResponse)
 
-	operation := api.MoneyOperationKafkaMessage{
-		Type:   api.Deposit,
+	return g.startOperation(model.Operation{
+		ID:     idempotencyKey,
 		UserID: claims.ID,
-		Diff:   diff,
-	}
-
-	raw, err := json.Marshal(operation)
-	if err != nil {
-		return &api.ErrorResponse{
-			Code:  http.StatusInternalServerError,
-			Error: "unable to deposit money",
-		}
-	}
-
-	err = g.producer.Produce("money-operations", raw)
-	if err != nil {
-		return utils.InterserviceCommunicationError()
-	}
-
-	return nil
+		Type:   model.OperationDeposit,
+	}, api.MoneyOperationKafkaMessage{
+		Type:           api.Deposit,
+		UserID:         claims.ID,
+		Amount:         diff,
+		IdempotencyKey: idempotencyKey,
+	})
 }
 
-func (g *gatewayUseCase) Buy(authHeader string) api.Response {
+func (g *gatewayUseCase) Buy(authHeader string, idempotencyKey string) api.Response {
 	authResponse := utils.Authorize(authHeader, g.authorizationPort)
 	if authResponse.GetCode() != http.StatusOK {
 		return authResponse
@@ -184,33 +277,155 @@ func (g *gatewayUseCase) Buy(authHeader string) api.Response {
 		return utils.InterserviceCommunicationError()
 	}
 
-	if orderResponse.Order.Orderer.Balance < orderResponse.Order.TotalPrice {
+	balance := orderResponse.Order.Orderer.Balance
+	price, conversionError := g.convert(ctx, orderResponse.Order.TotalPrice, balance.Currency)
+	if conversionError != nil {
+		return conversionError
+	}
+
+	// pay_for_order converts the total again with the rate current at the time
+	// of payment, this is only an early check
+	notEnough, _ := balance.Less(price)
+	if notEnough {
 		return &api.ErrorResponse{
 			Code:  http.StatusBadRequest,
 			Error: "not enough money on balance",
 		}
 	}
 
-	operation := api.MoneyOperationKafkaMessage{
-		Type:    api.Buy,
+	return g.startOperation(model.Operation{
+		ID:      idempotencyKey,
 		UserID:  claims.ID,
+		Type:    model.OperationBuy,
 		OrderID: orderResponse.Order.ID,
-	}
+	}, api.MoneyOperationKafkaMessage{
+		Type:           api.Buy,
+		UserID:         claims.ID,
+		OrderID:        orderResponse.Order.ID,
+		IdempotencyKey: idempotencyKey,
+	})
+}
 
-	raw, err := json.Marshal(operation)
+// startOperation records the operation as pending and hands the message over
+// to money-operations, which records the result. A request retried with the
+// same idempotency key gets the stored operation back, and the message is
+// produced again only while the operation is still pending, since
+// money-operations skips the ones it has already applied.
+func (g *gatewayUseCase) startOperation(operation model.Operation, message api.MoneyOperationKafkaMessage) api.Response {
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	stored, created, err := g.operations.StartOperation(ctx, operation)
 	if err != nil {
 		return &api.ErrorResponse{
 			Code:  http.StatusInternalServerError,
-			Error: "unable to buy order",
+			Error: "unable to start operation",
+		}
+	}
+
+	if stored.Type != operation.Type {
+		return &api.ErrorResponse{
+			Code:  http.StatusConflict,
+			Error: "idempotency key has already been used for another operation",
+		}
+	}
+
+	if !stored.IsPending() {
+		return &api.OperationResponse{
+			Code:      http.StatusOK,
+			Operation: stored,
 		}
 	}
 
-	err = g.producer.Produce("money-operations", raw)
+	raw, err := json.Marshal(message)
+	if err == nil {
+		err = g.producer.ProduceWithKey("money-operations", strconv.Itoa(message.UserID), raw)
+	}
 	if err != nil {
+		if created {
+			stored.Status = model.OperationFailed
+			stored.Reason = "unable to start operation"
+			g.operations.FinishOperation(ctx, stored)
+		}
 		return utils.InterserviceCommunicationError()
 	}
 
-	return nil
+	return &api.OperationResponse{
+		Code:      http.StatusAccepted,
+		Operation: stored,
+	}
+}
+
+// Operation returns the status of the user's operation. When wait is set and
+// the operation is still pending, it keeps rereading the status until the
+// operation finishes or wait runs out.
+func (g *gatewayUseCase) Operation(authHeader string, id string, wait time.Duration) api.Response {
+	authResponse := utils.Authorize(authHeader, g.authorizationPort)
+	if authResponse.GetCode() != http.StatusOK {
+		return authResponse
+	}
+
+	claims := authResponse.(*api.AuthorizationResponse)
+	deadline := time.Now().Add(wait)
+
+	for {
+		ctx, cancel := utils.DeadlineContext(5)
+		operation, err := g.operations.GetOperation(ctx, claims.ID, id)
+		cancel()
+
+		switch {
+		case err == repository.ErrOperationNotFound:
+			return &api.ErrorResponse{
+				Code:  http.StatusNotFound,
+				Error: "operation not found",
+			}
+		case err != nil:
+			return &api.ErrorResponse{
+				Code:  http.StatusInternalServerError,
+				Error: "unable to get operation",
+			}
+		}
+
+		if !operation.IsPending() || time.Now().Add(operationPollInterval).After(deadline) {
+			return &api.OperationResponse{
+				Code:      http.StatusOK,
+				Operation: operation,
+			}
+		}
+
+		time.Sleep(operationPollInterval)
+	}
+}
+
+// Notifications passes the Accept-Language header on, the messages are in the
+// language of the client
+func (g *gatewayUseCase) Notifications(authHeader, acceptLanguage, query string) (int, []byte) {
+	authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+	if authorizationResponse.GetCode() != http.StatusOK {
+		raw, _ := json.Marshal(authorizationResponse)
+		return authorizationResponse.GetCode(), raw
+	}
+
+	claims := authorizationResponse.(*api.AuthorizationResponse)
+
+	url := fmt.Sprintf("http://notifications:%s/users/%d/notifications", g.notificationsPort, claims.ID)
+	if query != "" {
+		url += "?" + query
+	}
+
+	return utils.RequestWithHeadersAndParseResponse("GET", url, map[string]string{"Accept-Language": acceptLanguage}, nil)
+}
+
+func (g *gatewayUseCase) MarkNotificationsRead(authHeader string, body io.Reader) (int, []byte) {
+	authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+	if authorizationResponse.GetCode() != http.StatusOK {
+		raw, _ := json.Marshal(authorizationResponse)
+		return authorizationResponse.GetCode(), raw
+	}
+
+	claims := authorizationResponse.(*api.AuthorizationResponse)
+
+	return utils.RequestAndParseResponse("PUT", fmt.Sprintf("http://notifications:%s/users/%d/notifications/read", g.notificationsPort, claims.ID), "", body)
 }
 
 func (g *gatewayUseCase) UserOrders(authHeader string) (int, []byte) {
@@ -225,12 +440,129 @@ func (g *gatewayUseCase) UserOrders(authHeader string) (int, []byte) {
 	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://order-management:%s/orders/%d", g.orderManagementPort, claims.ID), "", nil)
 }
 
-func (g *gatewayUseCase) MainPage(body io.Reader) (int, []byte) {
-	return utils.RequestAndParseResponse("POST", fmt.Sprintf("http://search-engine:%s/random", g.searchEnginePort), "", body)
+func (g *gatewayUseCase) CancelOrder(authHeader string) (int, []byte) {
+	authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+	if authorizationResponse.GetCode() != http.StatusOK {
+		raw, _ := json.Marshal(authorizationResponse)
+		return authorizationResponse.GetCode(), raw
+	}
+
+	claims := authorizationResponse.(*api.AuthorizationResponse)
+
+	return utils.RequestAndParseResponse("DELETE", fmt.Sprintf("http://order-management:%s/orders/%d", g.orderManagementPort, claims.ID), "", nil)
+}
+
+func (g *gatewayUseCase) AddToWishlist(authHeader string, albumID int) (int, []byte) {
+	return g.orderAction(albumID, authHeader, "wishlist/add")
 }
 
-func (g *gatewayUseCase) Search(body io.Reader) (int, []byte) {
-	return utils.RequestAndParseResponse("POST", fmt.Sprintf("http://search-engine:%s/search", g.searchEnginePort), "", body)
+func (g *gatewayUseCase) RemoveFromWishlist(authHeader string, albumID int) (int, []byte) {
+	return g.orderAction(albumID, authHeader, "wishlist/remove")
+}
+
+func (g *gatewayUseCase) Wishlist(authHeader string) (int, []byte) {
+	authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+	if authorizationResponse.GetCode() != http.StatusOK {
+		raw, _ := json.Marshal(authorizationResponse)
+		return authorizationResponse.GetCode(), raw
+	}
+
+	claims := authorizationResponse.(*api.AuthorizationResponse)
+
+	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://order-management:%s/wishlist/%d", g.orderManagementPort, claims.ID), "", nil)
+}
+
+func (g *gatewayUseCase) MoveWishlistToOrder(authHeader string) (int, []byte) {
+	authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+	if authorizationResponse.GetCode() != http.StatusOK {
+		raw, _ := json.Marshal(authorizationResponse)
+		return authorizationResponse.GetCode(), raw
+	}
+
+	claims := authorizationResponse.(*api.AuthorizationResponse)
+
+	return utils.RequestAndParseResponse("POST", fmt.Sprintf("http://order-management:%s/wishlist/%d/order", g.orderManagementPort, claims.ID), "", nil)
+}
+
+// MainPage recommends artists and albums to the caller, or shows popular ones
+// when there is no valid token.
+func (g *gatewayUseCase) MainPage(authHeader string, body io.Reader) (int, []byte) {
+	var request api.RecommendationsRequest
+	err := json.NewDecoder(body).Decode(&request)
+	if err != nil {
+		raw, _ := json.Marshal(api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid body in request",
+		})
+		return http.StatusBadRequest, raw
+	}
+
+	request.UserID = 0
+	if authHeader != "" {
+		authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+		if authorizationResponse.GetCode() == http.StatusOK {
+			request.UserID = authorizationResponse.(*api.AuthorizationResponse).ID
+		}
+	}
+
+	raw, err := json.Marshal(request)
+	if err != nil {
+		return utils.InterserviceCommunicationErrorRaw()
+	}
+
+	return utils.RequestAndParseResponse("POST", fmt.Sprintf("http://search-engine:%s/recommendations", g.searchEnginePort), "", bytes.NewReader(raw))
+}
+
+// Search passes the request on as a URL query. The user whose purchases are
+// filtered out always comes from the token, never from the request.
+func (g *gatewayUseCase) Search(authHeader string, request api.SearchRequest) (int, []byte) {
+	request.UserID = 0
+	if request.NotPurchased {
+		authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
+		if authorizationResponse.GetCode() != http.StatusOK {
+			raw, _ := json.Marshal(authorizationResponse)
+			return authorizationResponse.GetCode(), raw
+		}
+
+		request.UserID = authorizationResponse.(*api.AuthorizationResponse).ID
+	}
+
+	query := url.Values{}
+	setQuery := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	setQuery("q", request.Query)
+	setQuery("genre", request.Genre)
+	setQuery("minPrice", request.MinPrice.String())
+	setQuery("maxPrice", request.MaxPrice.String())
+	setQuery("sort", request.Sort)
+	setQuery("cursor", request.Cursor)
+	if request.ArtistID != 0 {
+		query.Set("artistID", strconv.Itoa(request.ArtistID))
+	}
+	if request.Limit != 0 {
+		query.Set("limit", strconv.FormatUint(uint64(request.Limit), 10))
+	}
+	if request.NotPurchased {
+		query.Set("notPurchased", "true")
+		query.Set("userID", strconv.Itoa(request.UserID))
+	}
+
+	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://search-engine:%s/search?%s", g.searchEnginePort, query.Encode()), "", nil)
+}
+
+func (g *gatewayUseCase) Suggest(query string) (int, []byte) {
+	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://search-engine:%s/suggest?%s", g.searchEnginePort, query), "", nil)
+}
+
+func (g *gatewayUseCase) Chart(query string) (int, []byte) {
+	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://search-engine:%s/charts?%s", g.searchEnginePort, query), "", nil)
+}
+
+func (g *gatewayUseCase) ArtistChartPositions(params string) (int, []byte) {
+	return utils.RequestAndParseResponse("GET", fmt.Sprintf("http://search-engine:%s/charts/artists/%s", g.searchEnginePort, params), "", nil)
 }
 
 func (g *gatewayUseCase) Logs(authHeader string, params string) (int, []byte) {
@@ -286,6 +618,12 @@ func (g *gatewayUseCase) LoadDump(authHeader, filePath string) (int, []byte) {
 		return http.StatusInternalServerError, raw
 	}
 
+	// the dump may have replaced the whole catalog
+	err = utils.ProduceCatalogMessage(api.CatalogKafkaMessage{}, g.producer)
+	if err != nil {
+		log.Printf("unable to produce catalog message: %s", err.Error())
+	}
+
 	// log.Print(string(output))
 	return http.StatusOK, output
 }
@@ -309,6 +647,76 @@ func (g *gatewayUseCase) AuthorizeAdmin(authHeader string) (int, []byte) {
 	return http.StatusOK, nil
 }
 
+func (g *gatewayUseCase) AddArtist(authHeader string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "POST", "artists", body)
+}
+
+func (g *gatewayUseCase) UpdateArtist(authHeader string, params string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "PUT", "artists/"+params, body)
+}
+
+func (g *gatewayUseCase) AddAlbum(authHeader string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "POST", "albums", body)
+}
+
+func (g *gatewayUseCase) UpdateAlbum(authHeader string, params string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "PUT", "albums/"+params, body)
+}
+
+func (g *gatewayUseCase) AddTrack(authHeader string, params string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "POST", fmt.Sprintf("albums/%s/tracks", params), body)
+}
+
+func (g *gatewayUseCase) UpdateTrack(authHeader string, params string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "PUT", "tracks/"+params, body)
+}
+
+func (g *gatewayUseCase) RevokeUserSessions(authHeader string, params string) (int, []byte) {
+	adminAuthorizationCode, raw := g.AuthorizeAdmin(authHeader)
+	if adminAuthorizationCode != http.StatusOK {
+		return adminAuthorizationCode, raw
+	}
+
+	return utils.RequestAndParseResponse("DELETE", fmt.Sprintf("http://authorization:%s/users/%s/sessions", g.authorizationPort, params), "", nil)
+}
+
+func (g *gatewayUseCase) DeadLetters(authHeader string, params string) (int, []byte) {
+	return g.adminAction(authHeader, "GET", "dead-letters/"+params, nil)
+}
+
+func (g *gatewayUseCase) Republish(authHeader string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "POST", "dead-letters/republish", body)
+}
+
+func (g *gatewayUseCase) RefundOrder(authHeader string, params string) (int, []byte) {
+	return g.adminAction(authHeader, "POST", "orders/"+params+"/refund", nil)
+}
+
+func (g *gatewayUseCase) AdminExchangeRates(authHeader string) (int, []byte) {
+	return g.adminAction(authHeader, "GET", "rates", nil)
+}
+
+func (g *gatewayUseCase) LoadExchangeRates(authHeader string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "POST", "rates", body)
+}
+
+func (g *gatewayUseCase) SetExchangeRate(authHeader string, params string, body io.Reader) (int, []byte) {
+	return g.adminAction(authHeader, "PUT", "rates/"+params, body)
+}
+
+func (g *gatewayUseCase) DeleteExchangeRate(authHeader string, params string) (int, []byte) {
+	return g.adminAction(authHeader, "DELETE", "rates/"+params, nil)
+}
+
+func (g *gatewayUseCase) adminAction(authHeader, method, path string, body io.Reader) (int, []byte) {
+	adminAuthorizationCode, raw := g.AuthorizeAdmin(authHeader)
+	if adminAuthorizationCode != http.StatusOK {
+		return adminAuthorizationCode, raw
+	}
+
+	return utils.RequestAndParseResponse(method, fmt.Sprintf("http://admin-panel:%s/%s", g.adminPanelPort, path), "", body)
+}
+
 func (g *gatewayUseCase) orderAction(albumID int, authHeader string, action string) (int, []byte) {
 	authorizationResponse := utils.Authorize(authHeader, g.authorizationPort)
 	if authorizationResponse.GetCode() != http.StatusOK {
@@ -327,3 +735,49 @@ func (g *gatewayUseCase) orderAction(albumID int, authHeader string, action stri
 
 	return utils.RequestAndParseResponse("POST", fmt.Sprintf("http://order-management:%s/%s", g.orderManagementPort, action), "", bytes.NewReader(body))
 }
+
+// convert converts money to the given currency with the current exchange rates.
+func (g *gatewayUseCase) convert(ctx context.Context, money model.Money, currency string) (model.Money, api.Response) {
+	if money.Currency == currency {
+		return money, nil
+	}
+
+	response, err := utils.Request(ctx, "GET", fmt.Sprintf("http://profile:%s/rates", g.profilePort), "", nil)
+	if err != nil {
+		return model.Money{}, utils.InterserviceCommunicationError()
+	}
+	defer response.Body.Close()
+
+	var ratesResponse api.ExchangeRatesResponse
+	err = json.NewDecoder(response.Body).Decode(&ratesResponse)
+	if err != nil || response.StatusCode != http.StatusOK {
+		return model.Money{}, utils.InterserviceCommunicationError()
+	}
+
+	var from, to *model.ExchangeRate
+	for i, rate := range ratesResponse.Rates {
+		switch rate.Currency {
+		case money.Currency:
+			from = &ratesResponse.Rates[i]
+		case currency:
+			to = &ratesResponse.Rates[i]
+		}
+	}
+
+	if from == nil || to == nil {
+		return model.Money{}, &api.ErrorResponse{
+			Code:  http.StatusExpectationFailed,
+			Error: fmt.Sprintf("no exchange rate from %s to %s", money.Currency, currency),
+		}
+	}
+
+	result, err := model.ConvertMoney(money, *from, *to)
+	if err != nil {
+		return model.Money{}, &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "unable to convert money",
+		}
+	}
+
+	return result, nil
+}
@@ -7,9 +7,16 @@ import (
 
 	"github.com/allnightmarel0Ng/albums/internal/app/money-operations/usecase"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
 )
 
+var operationTypes = map[api.MessageType]string{
+	api.Deposit: model.OperationDeposit,
+	api.Buy:     model.OperationBuy,
+	api.Refund:  model.OperationRefund,
+}
+
 type MoneyOperationsHandler interface {
 	Handle()
 }
@@ -27,32 +34,65 @@ func NewMoneyOperationsHandler(useCase usecase.MoneyOperationsUseCase, consumer
 }
 
 func (m *moneyOperationsHandler) Handle() {
-	m.consumer.ConsumeMessagesEternally(m.forkMessages, log.Printf, log.Printf)
+	m.consumer.OnDeadLetter(m.onDeadLetter)
+	m.consumer.ProcessMessagesEternally(m.processMessage, log.Printf, log.Printf)
 }
 
-func (m *moneyOperationsHandler) handleDeposit(userID int, diff uint) {
-	m.useCase.Deposit(userID, diff)
+// onDeadLetter finishes the operations which have run out of retries. The ones
+// failed permanently have got their outcome recorded already or don't belong
+// to any operation.
+func (m *moneyOperationsHandler) onDeadLetter(msg []byte, reason error) {
+	if kafka.IsPermanent(reason) {
+		return
+	}
+
+	var operation api.MoneyOperationKafkaMessage
+	if err := json.Unmarshal(msg, &operation); err != nil {
+		return
+	}
+
+	operationType, ok := operationTypes[operation.Type]
+	if !ok || operation.IdempotencyKey == "" {
+		return
+	}
+
+	m.useCase.QueueOperation(operation.UserID, operationType, operation.OrderID, operation.IdempotencyKey)
+}
+
+func (m *moneyOperationsHandler) handleDeposit(userID int, diff model.Money, operationKey string) error {
+	return m.useCase.Deposit(userID, diff, operationKey)
+}
+
+func (m *moneyOperationsHandler) handleBuy(userID, orderID int, operationKey string) error {
+	return m.useCase.BuyOrder(userID, orderID, operationKey)
 }
 
-func (m *moneyOperationsHandler) handleBuy(userID, orderID int) {
-	m.useCase.BuyOrder(userID, orderID)
+func (m *moneyOperationsHandler) handleRefund(userID, orderID int, operationKey string) error {
+	return m.useCase.RefundOrder(userID, orderID, operationKey)
 }
 
-func (m *moneyOperationsHandler) forkMessages(msg []byte) error {
+func (m *moneyOperationsHandler) processMessage(msg []byte) error {
 	log.Print(string(msg))
 	var operation api.MoneyOperationKafkaMessage
 	if err := json.Unmarshal(msg, &operation); err != nil {
-		return err
+		return kafka.Permanent(err)
+	}
+
+	if operation.IdempotencyKey == "" {
+		return kafka.Permanent(errors.New("missing idempotency key"))
 	}
 
 	switch operation.Type {
 	case api.Deposit:
-		go m.handleDeposit(operation.UserID, operation.Diff)
+		if operation.Amount.Amount <= 0 || !model.IsValidCurrency(operation.Amount.Currency) {
+			return kafka.Permanent(errors.New("invalid deposit amount"))
+		}
+		return m.handleDeposit(operation.UserID, operation.Amount, operation.IdempotencyKey)
 	case api.Buy:
-		go m.handleBuy(operation.UserID, operation.OrderID)
+		return m.handleBuy(operation.UserID, operation.OrderID, operation.IdempotencyKey)
+	case api.Refund:
+		return m.handleRefund(operation.UserID, operation.OrderID, operation.IdempotencyKey)
 	default:
-		return errors.New("unknown message type")
+		return kafka.Permanent(errors.New("unknown message type"))
 	}
-
-	return nil
 }
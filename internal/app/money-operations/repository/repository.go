@@ -3,38 +3,61 @@ package repository
 import (
 	"context"
 
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
 	"github.com/allnightmarel0Ng/albums/internal/domain/repository"
 )
 
 type MoneyOperationsRepository interface {
-	Deposit(ctx context.Context, id int, diff uint) error
-	BuyOrder(ctx context.Context, userID, albumID int) error
+	Deposit(ctx context.Context, id int, diff model.Money, operationKey string) error
+	BuyOrder(ctx context.Context, userID, albumID int, operationKey string) error
+	RefundOrder(ctx context.Context, userID, orderID int, operationKey string) error
+	FinishOperation(ctx context.Context, operation model.Operation) error
 }
 
 type moneyOperationsRepository struct {
-	users repository.UserRepository
+	users      repository.UserRepository
+	operations repository.OperationRepository
 }
 
-func NewMoneyOperationsRepository(users repository.UserRepository) MoneyOperationsRepository {
+func NewMoneyOperationsRepository(users repository.UserRepository, operations repository.OperationRepository) MoneyOperationsRepository {
 	return &moneyOperationsRepository{
-		users: users,
+		users:      users,
+		operations: operations,
 	}
 }
 
-func (m *moneyOperationsRepository) Deposit(ctx context.Context, id int, diff uint) error {
+func (m *moneyOperationsRepository) Deposit(ctx context.Context, id int, diff model.Money, operationKey string) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		return m.users.ChangeBalance(ctx, id, diff)
+		return m.users.ChangeBalance(ctx, id, diff, operationKey)
 	}
 }
 
-func (m *moneyOperationsRepository) BuyOrder(ctx context.Context, userID, orderID int) error {
+func (m *moneyOperationsRepository) BuyOrder(ctx context.Context, userID, orderID int, operationKey string) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		return m.users.PayForOrder(ctx, userID, orderID)
+		return m.users.PayForOrder(ctx, userID, orderID, operationKey)
+	}
+}
+
+func (m *moneyOperationsRepository) RefundOrder(ctx context.Context, userID, orderID int, operationKey string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return m.users.RefundOrder(ctx, userID, orderID, operationKey)
+	}
+}
+
+func (m *moneyOperationsRepository) FinishOperation(ctx context.Context, operation model.Operation) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return m.operations.FinishOperation(ctx, operation)
 	}
 }
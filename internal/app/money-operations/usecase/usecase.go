@@ -6,13 +6,18 @@ import (
 
 	"github.com/allnightmarel0Ng/albums/internal/app/money-operations/repository"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	domainRepository "github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
 )
 
 type MoneyOperationsUseCase interface {
-	Deposit(id int, diff uint)
-	BuyOrder(userID, albumID int)
+	Deposit(id int, diff model.Money, operationKey string) error
+	BuyOrder(userID, albumID int, operationKey string) error
+	RefundOrder(userID, orderID int, operationKey string) error
+	QueueOperation(userID int, operationType string, orderID int, operationKey string)
 }
 
 type moneyOperationsUseCase struct {
@@ -27,29 +32,53 @@ func NewMoneyOperationsUseCase(repo repository.MoneyOperationsRepository, produc
 	}
 }
 
-func (m *moneyOperationsUseCase) Deposit(id int, diff uint) {
-	err := m.repo.Deposit(context.Background(), id, diff)
-	if err != nil {
+func (m *moneyOperationsUseCase) Deposit(id int, diff model.Money, operationKey string) error {
+	operation := model.Operation{ID: operationKey, UserID: id, Type: model.OperationDeposit}
+
+	err := m.repo.Deposit(context.Background(), id, diff, operationKey)
+	switch {
+	case err == domainRepository.ErrAlreadyProcessed:
+		log.Printf("deposit %s has already been processed, skipping", operationKey)
+		m.finishOperation(operation, nil, "")
+		return nil
+	case postgres.IsTransient(err):
+		return err
+	case err != nil:
 		log.Printf("unable to deposit money: %s", err.Error())
 	}
 
+	m.finishOperation(operation, err, "deposit has been declined")
+
 	success := (err == nil)
 	err = utils.ProduceNotificationMessage(api.NotificationKafkaMessage{
 		Type:    api.Deposit,
 		UserID:  id,
+		Amount:  &diff,
 		Success: &success,
 	}, m.producer)
 	if err != nil {
 		log.Printf("unable to produce notification message: %s", err.Error())
 	}
+	return nil
 }
 
-func (m *moneyOperationsUseCase) BuyOrder(userID, orderID int) {
-	err := m.repo.BuyOrder(context.Background(), userID, orderID)
-	if err != nil {
-		log.Printf("unable to deposit money: %s", err.Error())
+func (m *moneyOperationsUseCase) BuyOrder(userID, orderID int, operationKey string) error {
+	operation := model.Operation{ID: operationKey, UserID: userID, Type: model.OperationBuy, OrderID: orderID}
+
+	err := m.repo.BuyOrder(context.Background(), userID, orderID, operationKey)
+	switch {
+	case err == domainRepository.ErrAlreadyProcessed:
+		log.Printf("purchase %s has already been processed, skipping", operationKey)
+		m.finishOperation(operation, nil, "")
+		return nil
+	case postgres.IsTransient(err):
+		return err
+	case err != nil:
+		log.Printf("unable to buy order: %s", err.Error())
 	}
 
+	m.finishOperation(operation, err, paymentDeclineReason(err))
+
 	success := (err == nil)
 	err = utils.ProduceNotificationMessage(api.NotificationKafkaMessage{
 		Type:    api.Buy,
@@ -60,5 +89,94 @@ func (m *moneyOperationsUseCase) BuyOrder(userID, orderID int) {
 	if err != nil {
 		log.Printf("unable to produce notification message: %s", err.Error())
 	}
+	return nil
+}
+
+// RefundOrder notifies the user only when the refund went through. A refund
+// that the database rejects is returned as a permanent error, so that it ends
+// up in the dead letters where the admin who requested it can see it.
+func (m *moneyOperationsUseCase) RefundOrder(userID, orderID int, operationKey string) error {
+	operation := model.Operation{ID: operationKey, UserID: userID, Type: model.OperationRefund, OrderID: orderID}
+
+	err := m.repo.RefundOrder(context.Background(), userID, orderID, operationKey)
+	switch {
+	case err == domainRepository.ErrAlreadyProcessed:
+		log.Printf("refund %s has already been processed, skipping", operationKey)
+		m.finishOperation(operation, nil, "")
+		return nil
+	case postgres.IsTransient(err):
+		return err
+	case err != nil:
+		log.Printf("unable to refund order: %s", err.Error())
+		m.finishOperation(operation, err, "refund has been declined")
+		return kafka.Permanent(err)
+	}
+
+	m.finishOperation(operation, nil, "")
+
+	success := true
+	err = utils.ProduceNotificationMessage(api.NotificationKafkaMessage{
+		Type:    api.Refund,
+		UserID:  userID,
+		OrderID: orderID,
+		Success: &success,
+	}, m.producer)
+	if err != nil {
+		log.Printf("unable to produce notification message: %s", err.Error())
+	}
+	return nil
+}
+
+// QueueOperation records that the operation has been moved to the dead letters
+// after failing on transient errors, so that the user doesn't wait for it.
+func (m *moneyOperationsUseCase) QueueOperation(userID int, operationType string, orderID int, operationKey string) {
+	operation := model.Operation{
+		ID:      operationKey,
+		UserID:  userID,
+		Type:    operationType,
+		Status:  model.OperationQueued,
+		Reason:  "operation can't be processed right now, it has been queued for review",
+		OrderID: orderID,
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
 
+	if err := m.repo.FinishOperation(ctx, operation); err != nil {
+		log.Printf("unable to save operation %s status: %s", operation.ID, err.Error())
+	}
+}
+
+// paymentDeclineReason tells the user why the order hasn't been paid
+func paymentDeclineReason(err error) string {
+	switch err {
+	case domainRepository.ErrAlbumPurchased:
+		return "order contains albums you already own"
+	case domainRepository.ErrInsufficientFunds:
+		return "not enough money on the balance"
+	case domainRepository.ErrOrderAlreadyPaid:
+		return "order has already been paid"
+	case domainRepository.ErrOrderNotFound:
+		return "order not found"
+	default:
+		return "payment has been declined, check your balance and order"
+	}
+}
+
+// finishOperation records the outcome of the operation so that the gateway
+// can report it. The reason is shown to the user, so it never contains the
+// database error itself.
+func (m *moneyOperationsUseCase) finishOperation(operation model.Operation, err error, reason string) {
+	operation.Status = model.OperationSucceeded
+	if err != nil {
+		operation.Status = model.OperationFailed
+		operation.Reason = reason
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	if err := m.repo.FinishOperation(ctx, operation); err != nil {
+		log.Printf("unable to save operation %s status: %s", operation.ID, err.Error())
+	}
 }
@@ -5,13 +5,33 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/allnightmarel0Ng/albums/internal/app/notifications/messages"
 	"github.com/allnightmarel0Ng/albums/internal/app/notifications/usecase"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
+const (
+	// writeWait is how long a single write to the client may take
+	writeWait = 10 * time.Second
+	// pongWait is how long the client may stay silent, pings keep it talking
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+
+	maxMessageSize = 4096
+
+	// eventsRetry is how soon an SSE client reconnects, in milliseconds
+	eventsRetry = 3000
+	// sentWindowSize is how many IDs of the notifications written to a stream
+	// are remembered to skip the repeated ones
+	sentWindowSize = 256
+)
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
@@ -20,6 +40,9 @@ var upgrader = websocket.Upgrader{
 
 type NotificationsHandler interface {
 	HandleNotifications(w http.ResponseWriter, r *http.Request)
+	HandleEvents(c *gin.Context)
+	HandleUserNotifications(c *gin.Context)
+	HandleMarkRead(c *gin.Context)
 }
 
 type notificationsHandler struct {
@@ -42,6 +65,12 @@ func (n *notificationsHandler) HandleNotifications(w http.ResponseWriter, r *htt
 	}
 	defer conn.Close()
 
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	msgType, msg, err := conn.ReadMessage()
 	if err != nil {
 		log.Printf("unable to read the message from client: %s", err.Error())
@@ -51,70 +80,313 @@ func (n *notificationsHandler) HandleNotifications(w http.ResponseWriter, r *htt
 	var subscription api.NotificationSubscribeRequest
 	err = json.Unmarshal(msg, &subscription)
 	if err != nil {
-		raw, _ := json.Marshal(api.ErrorResponse{
+		writeJSON(conn, msgType, api.ErrorResponse{
 			Code:  http.StatusBadRequest,
 			Error: "invalid subscription data",
 		})
-		conn.WriteMessage(msgType, raw)
 		log.Printf("invalid subscription data")
 		return
 	}
 
 	response := utils.Authorize("Bearer "+subscription.Jwt, n.authorizationPort)
 	if response.GetCode() != http.StatusOK {
-		raw, _ := json.Marshal(response)
-		conn.WriteMessage(msgType, raw)
+		writeJSON(conn, msgType, response)
 		log.Printf("unable to authorize the user")
 		return
 	}
 
 	claims := response.(*api.AuthorizationResponse)
-	end := make(chan bool)
+
+	// the client isn't expected to send anything else, but reading is what
+	// handles pongs and notices a closed connection
+	end := make(chan struct{})
 	go func() {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			log.Print("client disconnected")
-			end <- true
+		defer close(end)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				log.Print("client disconnected")
+				return
+			}
 		}
 	}()
 
-	notificationChannel := make(chan *api.NotificationKafkaMessage)
-	n.useCase.AddUser(claims.ID, notificationChannel)
+	locale := messages.Locale(r.Header.Get("Accept-Language"))
+	if subscription.Locale != "" {
+		locale = messages.Locale(subscription.Locale)
+	}
 
-	run := true
-	for run {
+	subscriber, err := n.useCase.Subscribe(claims.ID, locale)
+	if err != nil {
+		log.Printf("unable to subscribe user %d: %s", claims.ID, err.Error())
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "unable to subscribe"), time.Now().Add(writeWait))
+		return
+	}
+	defer n.useCase.Unsubscribe(subscriber)
+
+	// the ones that arrive meanwhile may come twice, clients tell them by ID
+	unread, err := n.useCase.Unread(claims.ID, locale)
+	if err != nil {
+		log.Printf("unable to get unread notifications: %s", err.Error())
+	}
+	for _, notification := range unread {
+		if err := writeJSON(conn, msgType, notification); err != nil {
+			log.Printf("unable to write message: %s", err.Error())
+			return
+		}
+	}
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
 		select {
 		case <-end:
-			run = false
-			n.useCase.DeleteUser(claims.ID)
-		case notification := <-notificationChannel:
-			var response api.NotificationResponse
-			response.Success = *notification.Success
-			response.Message = getMessage(notification)
-
-			raw, _ := json.Marshal(response)
-			err = conn.WriteMessage(msgType, raw)
-			if err != nil {
-				log.Println("unable to write message")
+			return
+		case <-subscriber.Evicted():
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(writeWait))
+			return
+		case notification := <-subscriber.Notifications():
+			if err := writeJSON(conn, msgType, notification); err != nil {
+				log.Printf("unable to write message: %s", err.Error())
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				log.Printf("unable to ping the client: %s", err.Error())
+				return
 			}
-			log.Print(raw)
 		}
 	}
 }
 
-func getMessage(notification *api.NotificationKafkaMessage) string {
-	switch notification.Type {
-	case api.Deposit:
-		if *notification.Success {
-			return "Money has been added to your account successfully"
+// HandleEvents streams the notifications as Server-Sent Events for the clients
+// that can't upgrade to a websocket. A reconnecting client sends the ID of the
+// last event it has got and receives everything after it.
+func (n *notificationsHandler) HandleEvents(c *gin.Context) {
+	response := utils.Authorize(c.GetHeader("Authorization"), n.authorizationPort)
+	if response.GetCode() != http.StatusOK {
+		utils.Send(c, response)
+		return
+	}
+
+	claims := response.(*api.AuthorizationResponse)
+
+	lastID := -1
+	if header := c.GetHeader("Last-Event-ID"); header != "" {
+		id, err := strconv.Atoi(header)
+		if err != nil || id < 0 {
+			utils.Send(c, &api.ErrorResponse{
+				Code:  http.StatusBadRequest,
+				Error: "invalid Last-Event-ID header",
+			})
+			return
+		}
+		lastID = id
+	}
+
+	locale := messages.Locale(c.GetHeader("Accept-Language"))
+
+	subscriber, err := n.useCase.Subscribe(claims.ID, locale)
+	if err != nil {
+		log.Printf("unable to subscribe user %d: %s", claims.ID, err.Error())
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusServiceUnavailable,
+			Error: "unable to subscribe",
+		})
+		return
+	}
+	defer n.useCase.Unsubscribe(subscriber)
+
+	var missed []api.NotificationResponse
+	if lastID >= 0 {
+		missed, err = n.useCase.After(claims.ID, lastID, locale)
+	} else {
+		missed, err = n.useCase.Unread(claims.ID, locale)
+	}
+	if err != nil {
+		log.Printf("unable to get missed notifications: %s", err.Error())
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	stream := http.NewResponseController(c.Writer)
+	write := func(format string, args ...interface{}) error {
+		stream.SetWriteDeadline(time.Now().Add(writeWait))
+		if _, err := fmt.Fprintf(c.Writer, format, args...); err != nil {
+			return err
+		}
+		return stream.Flush()
+	}
+
+	if err := write("retry: %d\n\n", eventsRetry); err != nil {
+		log.Printf("unable to write event: %s", err.Error())
+		return
+	}
+
+	// the ones that arrive meanwhile come from both the inbox and the
+	// subscription, replicas may publish them out of the ID order, so the
+	// repeated ones are told by their IDs
+	sent := newSentWindow(sentWindowSize)
+	if lastID >= 0 {
+		sent.add(lastID)
+	}
+	writeNotification := func(notification *api.NotificationResponse) error {
+		if !sent.add(notification.ID) {
+			return nil
+		}
+
+		raw, err := json.Marshal(notification)
+		if err != nil {
+			return err
+		}
+
+		return write("id: %d\nevent: notification\ndata: %s\n\n", notification.ID, raw)
+	}
+
+	for i := range missed {
+		if err := writeNotification(&missed[i]); err != nil {
+			log.Printf("unable to write event: %s", err.Error())
+			return
+		}
+	}
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-subscriber.Evicted():
+			return
+		case notification := <-subscriber.Notifications():
+			if err := writeNotification(notification); err != nil {
+				log.Printf("unable to write event: %s", err.Error())
+				return
+			}
+		case <-ticker.C:
+			// a comment keeps the proxies from closing an idle stream
+			if err := write(": ping\n\n"); err != nil {
+				log.Printf("unable to ping the client: %s", err.Error())
+				return
+			}
 		}
-		return "Money has not been added to your account"
-	case api.Buy:
-		if *notification.Success {
-			return fmt.Sprintf("Order %d has been paid successfully", notification.OrderID)
+	}
+}
+
+func (n *notificationsHandler) HandleUserNotifications(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid id parameter",
+		})
+		return
+	}
+
+	pageNumber, err := strconv.ParseUint(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || pageNumber == 0 {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid 'page' parameter",
+		})
+		return
+	}
+
+	pageSize, err := strconv.ParseUint(c.DefaultQuery("pageSize", "10"), 10, 64)
+	if err != nil || pageSize == 0 {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid 'pageSize' parameter",
+		})
+		return
+	}
+
+	unreadOnly, err := strconv.ParseBool(c.DefaultQuery("unreadOnly", "false"))
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid 'unreadOnly' parameter",
+		})
+		return
+	}
+
+	locale := messages.Locale(c.GetHeader("Accept-Language"))
+	utils.Send(c, n.useCase.Notifications(id, unreadOnly, uint(pageNumber), uint(pageSize), locale))
+}
+
+func (n *notificationsHandler) HandleMarkRead(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid id parameter",
+		})
+		return
+	}
+
+	var request api.MarkNotificationsReadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			utils.Send(c, &api.ErrorResponse{
+				Code:  http.StatusBadRequest,
+				Error: "invalid request body",
+			})
+			return
 		}
-		return fmt.Sprintf("Order %d has not been paid", notification.OrderID)
-	default:
-		return fmt.Sprintf("Album %s, that you owned, has been deleted", notification.AlbumName)
 	}
+
+	response := n.useCase.MarkRead(id, request.IDs)
+	if response != nil {
+		utils.Send(c, response)
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
+
+// sentWindow remembers the IDs of the last notifications written to a stream
+type sentWindow struct {
+	ids   map[int]struct{}
+	order []int
+	size  int
+}
+
+func newSentWindow(size int) *sentWindow {
+	return &sentWindow{
+		ids:   make(map[int]struct{}, size),
+		order: make([]int, 0, size),
+		size:  size,
+	}
+}
+
+// add reports whether the ID hasn't been sent yet, the oldest one is forgotten
+// once the window is full
+func (s *sentWindow) add(id int) bool {
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+
+	if len(s.order) == s.size {
+		delete(s.ids, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	s.ids[id] = struct{}{}
+	s.order = append(s.order, id)
+	return true
+}
+
+func writeJSON(conn *websocket.Conn, msgType int, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteMessage(msgType, raw)
 }
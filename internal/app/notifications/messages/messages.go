@@ -0,0 +1,90 @@
+package messages
+
+import (
+	"log"
+	"strings"
+	"text/template"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+)
+
+// DefaultLocale is used when the client asks for none of the known locales
+const DefaultLocale = "en"
+
+// sources are the message templates of every locale, keyed by the type and
+// the status of the notification. A new locale only needs an entry here.
+var sources = map[string]map[string]string{
+	"en": {
+		key(model.NotificationDeposit, model.NotificationSucceeded): "{{with .Amount}}{{.}} has{{else}}Money has{{end}} been added to your account successfully",
+		key(model.NotificationDeposit, model.NotificationFailed):    "{{with .Amount}}{{.}} has{{else}}Money has{{end}} not been added to your account",
+		key(model.NotificationBuy, model.NotificationSucceeded):     "Order {{.OrderID}} has been paid successfully",
+		key(model.NotificationBuy, model.NotificationFailed):        "Order {{.OrderID}} has not been paid",
+		key(model.NotificationRefund, model.NotificationSucceeded):  "Order {{.OrderID}} has been refunded",
+		key(model.NotificationRefund, model.NotificationFailed):     "Order {{.OrderID}} has not been refunded",
+		key(model.NotificationAlbumDeleted, model.NotificationInfo): "Album {{.AlbumName}}, that you owned, has been deleted",
+	},
+	"ru": {
+		key(model.NotificationDeposit, model.NotificationSucceeded): "{{with .Amount}}{{.}} зачислено{{else}}Деньги зачислены{{end}} на ваш счёт",
+		key(model.NotificationDeposit, model.NotificationFailed):    "{{with .Amount}}{{.}} не зачислено{{else}}Деньги не зачислены{{end}} на ваш счёт",
+		key(model.NotificationBuy, model.NotificationSucceeded):     "Заказ {{.OrderID}} успешно оплачен",
+		key(model.NotificationBuy, model.NotificationFailed):        "Заказ {{.OrderID}} не оплачен",
+		key(model.NotificationRefund, model.NotificationSucceeded):  "Деньги за заказ {{.OrderID}} возвращены",
+		key(model.NotificationRefund, model.NotificationFailed):     "Деньги за заказ {{.OrderID}} не возвращены",
+		key(model.NotificationAlbumDeleted, model.NotificationInfo): "Альбом {{.AlbumName}}, который был у вас, удалён",
+	},
+}
+
+var templates = parse(sources)
+
+// Locale picks the first known locale of an Accept-Language header, quality
+// values are ignored since the clients list the languages in their order
+// anyway.
+func Locale(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		language, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		language = strings.ToLower(language)
+
+		if _, ok := templates[language]; ok {
+			return language
+		}
+	}
+
+	return DefaultLocale
+}
+
+// Render builds the text of the notification in the locale, falling back to
+// the default one for the locales and the notifications it doesn't know.
+func Render(locale string, notification model.Notification) string {
+	tmpl, ok := templates[locale][key(notification.Type, notification.Status)]
+	if !ok {
+		tmpl, ok = templates[DefaultLocale][key(notification.Type, notification.Status)]
+	}
+	if !ok {
+		return notification.Type
+	}
+
+	var result strings.Builder
+	err := tmpl.Execute(&result, notification)
+	if err != nil {
+		log.Printf("unable to render notification %d: %s", notification.ID, err.Error())
+		return notification.Type
+	}
+
+	return result.String()
+}
+
+func key(notificationType, status string) string {
+	return notificationType + "/" + status
+}
+
+func parse(sources map[string]map[string]string) map[string]map[string]*template.Template {
+	result := make(map[string]map[string]*template.Template, len(sources))
+	for locale, messages := range sources {
+		result[locale] = make(map[string]*template.Template, len(messages))
+		for name, text := range messages {
+			result[locale][name] = template.Must(template.New(locale + ":" + name).Parse(text))
+		}
+	}
+	return result
+}
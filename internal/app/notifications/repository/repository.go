@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/domain/repository"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/redis"
+)
+
+// every user has a channel of their own, a replica listens only to the ones of
+// the users connected to it
+const userChannelPrefix = "notifications:"
+
+type NotificationsRepository interface {
+	SaveNotification(ctx context.Context, notification model.Notification) (model.Notification, error)
+	GetNotificationsAndCount(ctx context.Context, userID int, unreadOnly bool, offset, limit uint) (uint, []model.Notification, error)
+	GetUnreadNotifications(ctx context.Context, userID int, limit uint) ([]model.Notification, error)
+	GetNotificationsAfter(ctx context.Context, userID, afterID int, limit uint) ([]model.Notification, error)
+	MarkRead(ctx context.Context, userID int, ids []int) error
+	PublishNotification(ctx context.Context, notification model.Notification) error
+	WatchUser(ctx context.Context, userID int) error
+	UnwatchUser(ctx context.Context, userID int) error
+	PublishedNotifications() <-chan model.Notification
+}
+
+type notificationsRepository struct {
+	notifications repository.NotificationRepository
+	client        redis.Client
+	pubSub        redis.PubSub
+	published     chan model.Notification
+}
+
+func NewNotificationsRepository(notifications repository.NotificationRepository, client redis.Client) NotificationsRepository {
+	result := &notificationsRepository{
+		notifications: notifications,
+		client:        client,
+		pubSub:        client.NewPubSub(context.Background()),
+		published:     make(chan model.Notification),
+	}
+
+	go result.decodePublished()
+
+	return result
+}
+
+func (n *notificationsRepository) SaveNotification(ctx context.Context, notification model.Notification) (model.Notification, error) {
+	select {
+	case <-ctx.Done():
+		return model.Notification{}, ctx.Err()
+	default:
+		return n.notifications.AddNotification(ctx, notification)
+	}
+}
+
+func (n *notificationsRepository) GetNotificationsAndCount(ctx context.Context, userID int, unreadOnly bool, offset, limit uint) (uint, []model.Notification, error) {
+	select {
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	default:
+		count, err := n.notifications.GetNotificationsCount(ctx, userID, unreadOnly)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		notifications, err := n.notifications.GetNotifications(ctx, userID, unreadOnly, offset, limit)
+		return count, notifications, err
+	}
+}
+
+func (n *notificationsRepository) GetUnreadNotifications(ctx context.Context, userID int, limit uint) ([]model.Notification, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		return n.notifications.GetUnreadNotifications(ctx, userID, limit)
+	}
+}
+
+func (n *notificationsRepository) GetNotificationsAfter(ctx context.Context, userID, afterID int, limit uint) ([]model.Notification, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		return n.notifications.GetNotificationsAfter(ctx, userID, afterID, limit)
+	}
+}
+
+func (n *notificationsRepository) MarkRead(ctx context.Context, userID int, ids []int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return n.notifications.MarkNotificationsRead(ctx, userID, ids)
+	}
+}
+
+// PublishNotification hands the saved notification to the replicas which have
+// the user connected
+func (n *notificationsRepository) PublishNotification(ctx context.Context, notification model.Notification) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		raw, err := json.Marshal(notification)
+		if err != nil {
+			return err
+		}
+
+		return n.client.Publish(ctx, userChannel(notification.UserID), raw)
+	}
+}
+
+func (n *notificationsRepository) WatchUser(ctx context.Context, userID int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return n.pubSub.Subscribe(ctx, userChannel(userID))
+	}
+}
+
+func (n *notificationsRepository) UnwatchUser(ctx context.Context, userID int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return n.pubSub.Unsubscribe(ctx, userChannel(userID))
+	}
+}
+
+// PublishedNotifications returns the notifications of the watched users
+// published by any replica
+func (n *notificationsRepository) PublishedNotifications() <-chan model.Notification {
+	return n.published
+}
+
+func (n *notificationsRepository) decodePublished() {
+	defer close(n.published)
+
+	for msg := range n.pubSub.Messages() {
+		userID, err := strconv.Atoi(strings.TrimPrefix(msg.Channel, userChannelPrefix))
+		if err != nil {
+			log.Printf("unexpected channel %s", msg.Channel)
+			continue
+		}
+
+		var notification model.Notification
+		err = json.Unmarshal([]byte(msg.Payload), &notification)
+		if err != nil {
+			log.Printf("unable to decode the notification: %s", err.Error())
+			continue
+		}
+
+		notification.UserID = userID
+		n.published <- notification
+	}
+}
+
+func userChannel(userID int) string {
+	return fmt.Sprintf("%s%d", userChannelPrefix, userID)
+}
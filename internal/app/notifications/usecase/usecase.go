@@ -4,63 +4,375 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"sync"
 
+	"github.com/allnightmarel0Ng/albums/internal/app/notifications/messages"
+	"github.com/allnightmarel0Ng/albums/internal/app/notifications/repository"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
 	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+	"github.com/allnightmarel0Ng/albums/internal/utils"
 )
 
+const (
+	// unreadReplayLimit is how many unread notifications a new subscriber gets
+	unreadReplayLimit = 100
+
+	// subscriberBuffer is how many notifications may wait for a client before
+	// it is considered too slow and evicted
+	subscriberBuffer = 16
+)
+
+var notificationTypes = map[api.MessageType]string{
+	api.Deposit: model.NotificationDeposit,
+	api.Buy:     model.NotificationBuy,
+	api.Refund:  model.NotificationRefund,
+	api.Delete:  model.NotificationAlbumDeleted,
+}
+
+// Subscriber is a single connection of a user, a user may have several of
+// them, e.g. in different tabs, each in a locale of its own.
+type Subscriber struct {
+	userID        int
+	locale        string
+	notifications chan *api.NotificationResponse
+	evicted       chan struct{}
+}
+
+func (s *Subscriber) Notifications() <-chan *api.NotificationResponse {
+	return s.notifications
+}
+
+// Evicted is closed when the subscriber has fallen too far behind. It misses
+// the notifications since then, so the connection has to be closed to let the
+// client resubscribe and get them from the inbox.
+func (s *Subscriber) Evicted() <-chan struct{} {
+	return s.evicted
+}
+
 type NotificationsUseCase interface {
-	AddUser(userID int, channel chan<- *api.NotificationKafkaMessage)
-	DeleteUser(userID int)
+	Subscribe(userID int, locale string) (*Subscriber, error)
+	Unsubscribe(subscriber *Subscriber)
 	Consume()
+	Deliver()
+	Unread(userID int, locale string) ([]api.NotificationResponse, error)
+	After(userID, lastID int, locale string) ([]api.NotificationResponse, error)
+	Notifications(userID int, unreadOnly bool, pageNumber, pageSize uint, locale string) api.Response
+	MarkRead(userID int, ids []int) api.Response
 }
 
+// notificationsUseCase keeps the subscribers under mu, which the delivery
+// holds, and talks to Redis only under watchMu, so that a slow Redis never
+// stalls the delivery.
 type notificationsUseCase struct {
-	consumer *kafka.Consumer
-	channels map[int]chan<- *api.NotificationKafkaMessage
-	mu       sync.Mutex
+	repo        repository.NotificationsRepository
+	consumer    *kafka.Consumer
+	subscribers map[int]map[*Subscriber]struct{}
+	mu          sync.Mutex
+	// watched are the users whose notifications this replica listens to
+	watched map[int]bool
+	watchMu sync.Mutex
 }
 
-func NewNotificationsUseCase(consumer *kafka.Consumer) NotificationsUseCase {
+func NewNotificationsUseCase(repo repository.NotificationsRepository, consumer *kafka.Consumer) NotificationsUseCase {
 	return &notificationsUseCase{
-		consumer: consumer,
-		channels: make(map[int]chan<- *api.NotificationKafkaMessage),
+		repo:        repo,
+		consumer:    consumer,
+		subscribers: make(map[int]map[*Subscriber]struct{}),
+		watched:     make(map[int]bool),
 	}
 }
 
-func (n *notificationsUseCase) AddUser(userID int, channel chan<- *api.NotificationKafkaMessage) {
+// Subscribe starts listening to the notifications of the user published by
+// any replica once the user has the first connection to this one.
+func (n *notificationsUseCase) Subscribe(userID int, locale string) (*Subscriber, error) {
+	subscriber := &Subscriber{
+		userID:        userID,
+		locale:        locale,
+		notifications: make(chan *api.NotificationResponse, subscriberBuffer),
+		evicted:       make(chan struct{}),
+	}
+
 	n.mu.Lock()
-	defer n.mu.Unlock()
-	n.channels[userID] = channel
+	if n.subscribers[userID] == nil {
+		n.subscribers[userID] = make(map[*Subscriber]struct{})
+	}
+	n.subscribers[userID][subscriber] = struct{}{}
+	n.mu.Unlock()
+
+	if err := n.syncWatch(userID); err != nil {
+		n.Unsubscribe(subscriber)
+		return nil, err
+	}
+
+	return subscriber, nil
 }
 
-func (n *notificationsUseCase) DeleteUser(userID int) {
+func (n *notificationsUseCase) Unsubscribe(subscriber *Subscriber) {
 	n.mu.Lock()
-	defer n.mu.Unlock()
-	delete(n.channels, userID)
+	last := n.remove(subscriber)
+	n.mu.Unlock()
+
+	if last {
+		n.unwatch(subscriber.userID)
+	}
 }
 
-func (n *notificationsUseCase) Consume() {
-	n.consumer.ConsumeMessagesEternally(n.onConsume, log.Printf, log.Printf)
+func (n *notificationsUseCase) unwatch(userID int) {
+	if err := n.syncWatch(userID); err != nil {
+		log.Printf("unable to stop watching user %d: %s", userID, err.Error())
+	}
 }
 
-func (n *notificationsUseCase) onConsume(msg []byte) error {
+// syncWatch makes the replica listen to the notifications of the user while
+// the user has connections to it. It reads the connections after taking
+// watchMu, so the last call leaves the right state whatever order the calls
+// have run in.
+func (n *notificationsUseCase) syncWatch(userID int) error {
+	n.watchMu.Lock()
+	defer n.watchMu.Unlock()
+
 	n.mu.Lock()
-	defer n.mu.Unlock()
+	connected := len(n.subscribers[userID]) != 0
+	n.mu.Unlock()
 
-	var notification api.NotificationKafkaMessage
-	err := json.Unmarshal(msg, &notification)
+	if connected == n.watched[userID] {
+		return nil
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	var err error
+	if connected {
+		err = n.repo.WatchUser(ctx, userID)
+	} else {
+		err = n.repo.UnwatchUser(ctx, userID)
+	}
 	if err != nil {
 		return err
 	}
 
-	_, ok := n.channels[notification.UserID]
+	if connected {
+		n.watched[userID] = true
+	} else {
+		delete(n.watched, userID)
+	}
+	return nil
+}
+
+// publish hands the notification to every connection of the user without
+// waiting for any of them, it is rendered once per locale
+func (n *notificationsUseCase) publish(notification model.Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// the delivery doesn't wait for Redis
+	last := false
+	defer func() {
+		if last {
+			go n.unwatch(notification.UserID)
+		}
+	}()
+
+	rendered := make(map[string]*api.NotificationResponse)
+	for subscriber := range n.subscribers[notification.UserID] {
+		response, ok := rendered[subscriber.locale]
+		if !ok {
+			result := notificationResponse(notification, subscriber.locale)
+			response = &result
+			rendered[subscriber.locale] = response
+		}
+
+		select {
+		case subscriber.notifications <- response:
+		default:
+			log.Printf("evicting a slow subscriber of user %d", notification.UserID)
+			last = n.remove(subscriber)
+			close(subscriber.evicted)
+		}
+	}
+}
+
+// remove reports whether the subscriber has been the last one of the user,
+// the caller stops watching the user then, without holding mu
+func (n *notificationsUseCase) remove(subscriber *Subscriber) bool {
+	subscribers, ok := n.subscribers[subscriber.userID]
 	if !ok {
-		return fmt.Errorf("no such user with ID: %d", notification.UserID)
+		return false
 	}
 
-	n.channels[notification.UserID] <- &notification
+	delete(subscribers, subscriber)
+	if len(subscribers) != 0 {
+		return false
+	}
+
+	delete(n.subscribers, subscriber.userID)
+	return true
+}
+
+// Consume saves every notification before its offset is committed, the users
+// who aren't connected get it from the inbox later. A redelivered notification
+// is saved once, by its key. Replicas share the
+// consumer group, so every notification is consumed by one of them and then
+// published to the one which the user is connected to.
+func (n *notificationsUseCase) Consume() {
+	n.consumer.ProcessMessagesEternally(n.onConsume, log.Printf, log.Printf)
+}
+
+func (n *notificationsUseCase) onConsume(msg []byte) error {
+	var message api.NotificationKafkaMessage
+	err := json.Unmarshal(msg, &message)
+	if err != nil {
+		return kafka.Permanent(err)
+	}
+
+	notificationType, ok := notificationTypes[message.Type]
+	if !ok {
+		return kafka.Permanent(fmt.Errorf("unknown notification type: %d", message.Type))
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	notification, err := n.repo.SaveNotification(ctx, model.Notification{
+		Key:       message.Key,
+		UserID:    message.UserID,
+		Type:      notificationType,
+		Status:    notificationStatus(message),
+		OrderID:   message.OrderID,
+		AlbumID:   message.AlbumID,
+		AlbumName: message.AlbumName,
+		Amount:    message.Amount,
+		CreatedAt: message.Time,
+	})
+	if err != nil {
+		if postgres.IsTransient(err) {
+			return err
+		}
+		return kafka.Permanent(err)
+	}
+
+	// the notification is in the inbox already, so the users who miss it get
+	// it on their next subscription
+	err = n.repo.PublishNotification(ctx, notification)
+	if err != nil {
+		log.Printf("unable to publish notification %d: %s", notification.ID, err.Error())
+	}
+
+	return nil
+}
+
+// Deliver passes the published notifications to the connections of their users
+// to this replica.
+func (n *notificationsUseCase) Deliver() {
+	for notification := range n.repo.PublishedNotifications() {
+		n.publish(notification)
+	}
+}
+
+// Unread returns the oldest unread notifications of the user, so that the ones
+// sent while the user was away get delivered on subscription.
+func (n *notificationsUseCase) Unread(userID int, locale string) ([]api.NotificationResponse, error) {
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	notifications, err := n.repo.GetUnreadNotifications(ctx, userID, unreadReplayLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return notificationResponses(notifications, locale), nil
+}
+
+// After returns the notifications that came after the last one the client has
+// seen, read or not, to resume an interrupted stream. The unread ones are
+// returned too, since one with a lower ID may have been published later.
+func (n *notificationsUseCase) After(userID, lastID int, locale string) ([]api.NotificationResponse, error) {
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	notifications, err := n.repo.GetNotificationsAfter(ctx, userID, lastID, unreadReplayLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return notificationResponses(notifications, locale), nil
+}
+
+func (n *notificationsUseCase) Notifications(userID int, unreadOnly bool, pageNumber, pageSize uint, locale string) api.Response {
+	offset := (pageNumber - 1) * pageSize
+	limit := pageSize
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	count, notifications, err := n.repo.GetNotificationsAndCount(ctx, userID, unreadOnly, offset, limit)
+	if err != nil {
+		log.Print(err.Error())
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "database communication error",
+		}
+	}
+
+	return &api.NotificationsResponse{
+		Code:          http.StatusOK,
+		Notifications: notificationResponses(notifications, locale),
+		Count:         count,
+	}
+}
+
+func (n *notificationsUseCase) MarkRead(userID int, ids []int) api.Response {
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	err := n.repo.MarkRead(ctx, userID, ids)
+	if err != nil {
+		log.Print(err.Error())
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "database communication error",
+		}
+	}
 
 	return nil
 }
+
+// notificationStatus tells the outcome of an operation, deleted albums were
+// once sent as failures, so their status doesn't depend on Success
+func notificationStatus(message api.NotificationKafkaMessage) string {
+	switch {
+	case message.Type == api.Delete:
+		return model.NotificationInfo
+	case message.Success != nil && *message.Success:
+		return model.NotificationSucceeded
+	default:
+		return model.NotificationFailed
+	}
+}
+
+func notificationResponses(notifications []model.Notification, locale string) []api.NotificationResponse {
+	result := make([]api.NotificationResponse, len(notifications))
+	for i, notification := range notifications {
+		result[i] = notificationResponse(notification, locale)
+	}
+	return result
+}
+
+func notificationResponse(notification model.Notification, locale string) api.NotificationResponse {
+	return api.NotificationResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Status:    notification.Status,
+		OrderID:   notification.OrderID,
+		AlbumID:   notification.AlbumID,
+		AlbumName: notification.AlbumName,
+		Amount:    notification.Amount,
+		Message:   messages.Render(locale, notification),
+		IsRead:    notification.IsRead,
+		CreatedAt: notification.CreatedAt,
+	}
+}
@@ -0,0 +1,352 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allnightmarel0Ng/albums/internal/app/notifications/messages"
+	"github.com/allnightmarel0Ng/albums/internal/app/notifications/repository"
+	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/redis"
+)
+
+func TestNotificationReachesUserConnectedToAnotherReplica(t *testing.T) {
+	inbox := newFakeInbox()
+	server := newFakeRedis()
+
+	replicaA := newReplica(inbox, server)
+	replicaB := newReplica(inbox, server)
+
+	subscriber, err := replicaA.Subscribe(42, messages.DefaultLocale)
+	if err != nil {
+		t.Fatalf("unable to subscribe: %s", err.Error())
+	}
+	defer replicaA.Unsubscribe(subscriber)
+
+	err = replicaB.onConsume(notificationMessage(t, "buy-7", 42, 7))
+	if err != nil {
+		t.Fatalf("unable to consume: %s", err.Error())
+	}
+
+	select {
+	case notification := <-subscriber.Notifications():
+		if notification.Type != model.NotificationBuy || notification.Status != model.NotificationSucceeded || notification.OrderID != 7 {
+			t.Errorf("unexpected notification: %+v", notification)
+		}
+		if notification.ID != inbox.saved()[0].ID {
+			t.Errorf("got notification %d, saved %d", notification.ID, inbox.saved()[0].ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the notification hasn't reached the replica the user is connected to")
+	}
+}
+
+func TestNotificationIsSavedForUserConnectedNowhere(t *testing.T) {
+	inbox := newFakeInbox()
+	server := newFakeRedis()
+
+	replicaA := newReplica(inbox, server)
+	replicaB := newReplica(inbox, server)
+
+	subscriber, err := replicaA.Subscribe(42, messages.DefaultLocale)
+	if err != nil {
+		t.Fatalf("unable to subscribe: %s", err.Error())
+	}
+	replicaA.Unsubscribe(subscriber)
+
+	if channels := server.subscribedChannels(); len(channels) != 0 {
+		t.Errorf("channels are still watched after the last connection has gone: %v", channels)
+	}
+
+	err = replicaB.onConsume(notificationMessage(t, "buy-7", 42, 7))
+	if err != nil {
+		t.Fatalf("unable to consume: %s", err.Error())
+	}
+
+	if saved := inbox.saved(); len(saved) != 1 || saved[0].UserID != 42 {
+		t.Errorf("unexpected inbox: %+v", saved)
+	}
+}
+
+func TestRedeliveredNotificationIsDeliveredOnce(t *testing.T) {
+	inbox := newFakeInbox()
+	server := newFakeRedis()
+
+	replicaA := newReplica(inbox, server)
+	replicaB := newReplica(inbox, server)
+
+	subscriber, err := replicaA.Subscribe(42, messages.DefaultLocale)
+	if err != nil {
+		t.Fatalf("unable to subscribe: %s", err.Error())
+	}
+	defer replicaA.Unsubscribe(subscriber)
+
+	// a rebalance hands the partition to another replica before the offset
+	// has been committed
+	msg := notificationMessage(t, "buy-7", 42, 7)
+	for _, replica := range []*notificationsUseCase{replicaB, replicaA} {
+		if err := replica.onConsume(msg); err != nil {
+			t.Fatalf("unable to consume: %s", err.Error())
+		}
+	}
+
+	if saved := inbox.saved(); len(saved) != 1 {
+		t.Fatalf("the notification has been saved %d times", len(saved))
+	}
+
+	// the message is published on both consumptions, clients tell the
+	// repeated one by its ID
+	var first *api.NotificationResponse
+	select {
+	case first = <-subscriber.Notifications():
+	case <-time.After(time.Second):
+		t.Fatal("the notification hasn't been delivered")
+	}
+
+	timeout := time.After(100 * time.Millisecond)
+	for {
+		select {
+		case notification := <-subscriber.Notifications():
+			if notification.ID != first.ID {
+				t.Fatalf("got notifications %d and %d for one message", first.ID, notification.ID)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+func newReplica(inbox *fakeInbox, server *fakeRedis) *notificationsUseCase {
+	repo := repository.NewNotificationsRepository(inbox, &fakeRedisClient{server: server})
+	result := NewNotificationsUseCase(repo, nil).(*notificationsUseCase)
+
+	go result.Deliver()
+
+	return result
+}
+
+func notificationMessage(t *testing.T, key string, userID, orderID int) []byte {
+	success := true
+	raw, err := json.Marshal(api.NotificationKafkaMessage{
+		Key:     key,
+		Type:    api.Buy,
+		UserID:  userID,
+		OrderID: orderID,
+		Success: &success,
+	})
+	if err != nil {
+		t.Fatalf("unable to encode the message: %s", err.Error())
+	}
+	return raw
+}
+
+// fakeInbox stands in for the notifications table shared by the replicas
+type fakeInbox struct {
+	mu            sync.Mutex
+	notifications []model.Notification
+}
+
+func newFakeInbox() *fakeInbox {
+	return &fakeInbox{}
+}
+
+func (f *fakeInbox) saved() []model.Notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]model.Notification(nil), f.notifications...)
+}
+
+func (f *fakeInbox) AddNotification(ctx context.Context, notification model.Notification) (model.Notification, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, saved := range f.notifications {
+		if notification.Key != "" && saved.Key == notification.Key {
+			return saved, nil
+		}
+	}
+
+	notification.ID = len(f.notifications) + 1
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	f.notifications = append(f.notifications, notification)
+	return notification, nil
+}
+
+func (f *fakeInbox) GetNotifications(ctx context.Context, userID int, unreadOnly bool, offset, limit uint) ([]model.Notification, error) {
+	return nil, nil
+}
+
+func (f *fakeInbox) GetNotificationsCount(ctx context.Context, userID int, unreadOnly bool) (uint, error) {
+	return 0, nil
+}
+
+func (f *fakeInbox) GetUnreadNotifications(ctx context.Context, userID int, limit uint) ([]model.Notification, error) {
+	return nil, nil
+}
+
+func (f *fakeInbox) GetNotificationsAfter(ctx context.Context, userID, afterID int, limit uint) ([]model.Notification, error) {
+	return nil, nil
+}
+
+func (f *fakeInbox) MarkNotificationsRead(ctx context.Context, userID int, ids []int) error {
+	return nil
+}
+
+// fakeRedis stands in for the Redis server the replicas share, only its
+// publish/subscribe is implemented
+type fakeRedis struct {
+	mu      sync.Mutex
+	pubSubs map[*fakePubSub]struct{}
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		pubSubs: make(map[*fakePubSub]struct{}),
+	}
+}
+
+func (f *fakeRedis) publish(channel, payload string) {
+	f.mu.Lock()
+	receivers := make([]*fakePubSub, 0)
+	for pubSub := range f.pubSubs {
+		if pubSub.channels[channel] {
+			receivers = append(receivers, pubSub)
+		}
+	}
+	f.mu.Unlock()
+
+	for _, pubSub := range receivers {
+		pubSub.messages <- redis.Message{Channel: channel, Payload: payload}
+	}
+}
+
+func (f *fakeRedis) subscribedChannels() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make([]string, 0)
+	for pubSub := range f.pubSubs {
+		for channel, subscribed := range pubSub.channels {
+			if subscribed {
+				result = append(result, channel)
+			}
+		}
+	}
+	return result
+}
+
+type fakeRedisClient struct {
+	server *fakeRedis
+}
+
+func (f *fakeRedisClient) Publish(ctx context.Context, channel string, message interface{}) error {
+	switch payload := message.(type) {
+	case []byte:
+		f.server.publish(channel, string(payload))
+	case string:
+		f.server.publish(channel, payload)
+	default:
+		return redis.ErrRedis
+	}
+	return nil
+}
+
+func (f *fakeRedisClient) NewPubSub(ctx context.Context) redis.PubSub {
+	result := &fakePubSub{
+		server:   f.server,
+		channels: make(map[string]bool),
+		messages: make(chan redis.Message, 64),
+	}
+
+	f.server.mu.Lock()
+	f.server.pubSubs[result] = struct{}{}
+	f.server.mu.Unlock()
+
+	return result
+}
+
+func (f *fakeRedisClient) Set(ctx context.Context, key string, value interface{}, exp time.Duration) error {
+	return redis.ErrRedis
+}
+
+func (f *fakeRedisClient) SetNX(ctx context.Context, key string, value interface{}, exp time.Duration) (bool, error) {
+	return false, redis.ErrRedis
+}
+
+func (f *fakeRedisClient) Get(ctx context.Context, key string) (string, error) {
+	return "", redis.ErrNotFound
+}
+
+func (f *fakeRedisClient) GetDel(ctx context.Context, key string) (string, error) {
+	return "", redis.ErrNotFound
+}
+
+func (f *fakeRedisClient) Del(ctx context.Context, keys ...string) error {
+	return nil
+}
+
+func (f *fakeRedisClient) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	return redis.ErrRedis
+}
+
+func (f *fakeRedisClient) SRem(ctx context.Context, key string, members ...interface{}) error {
+	return redis.ErrRedis
+}
+
+func (f *fakeRedisClient) SMembers(ctx context.Context, key string) ([]string, error) {
+	return nil, redis.ErrRedis
+}
+
+func (f *fakeRedisClient) Close() error {
+	return nil
+}
+
+func (f *fakeRedisClient) Ping(ctx context.Context) error {
+	return nil
+}
+
+type fakePubSub struct {
+	server   *fakeRedis
+	channels map[string]bool
+	messages chan redis.Message
+}
+
+func (f *fakePubSub) Subscribe(ctx context.Context, channels ...string) error {
+	f.server.mu.Lock()
+	defer f.server.mu.Unlock()
+
+	for _, channel := range channels {
+		f.channels[channel] = true
+	}
+	return nil
+}
+
+func (f *fakePubSub) Unsubscribe(ctx context.Context, channels ...string) error {
+	f.server.mu.Lock()
+	defer f.server.mu.Unlock()
+
+	for _, channel := range channels {
+		delete(f.channels, channel)
+	}
+	return nil
+}
+
+func (f *fakePubSub) Messages() <-chan redis.Message {
+	return f.messages
+}
+
+func (f *fakePubSub) Close() error {
+	f.server.mu.Lock()
+	defer f.server.mu.Unlock()
+
+	delete(f.server.pubSubs, f)
+	close(f.messages)
+	return nil
+}
@@ -17,6 +17,11 @@ type OrderManagementHandler interface {
 	HandleAdd(c *gin.Context)
 	HandleRemove(c *gin.Context)
 	HandleOrders(c *gin.Context)
+	HandleCancel(c *gin.Context)
+	HandleWishlistAdd(c *gin.Context)
+	HandleWishlistRemove(c *gin.Context)
+	HandleWishlist(c *gin.Context)
+	HandleMoveWishlist(c *gin.Context)
 }
 
 type orderManagementHandler struct {
@@ -96,6 +101,84 @@ func (o *orderManagementHandler) HandleOrders(c *gin.Context) {
 	c.String(http.StatusOK, "")
 }
 
+func (o *orderManagementHandler) HandleCancel(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid id parameter",
+		})
+		return
+	}
+
+	response := o.useCase.CancelOrder(id)
+	if response != nil {
+		utils.Send(c, response)
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
+
+func (o *orderManagementHandler) HandleWishlistAdd(c *gin.Context) {
+	o.handleWishlistAction(c, o.useCase.AddAlbumToWishlist)
+}
+
+func (o *orderManagementHandler) HandleWishlistRemove(c *gin.Context) {
+	o.handleWishlistAction(c, o.useCase.RemoveAlbumFromWishlist)
+}
+
+func (o *orderManagementHandler) HandleWishlist(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid id parameter",
+		})
+		return
+	}
+
+	utils.Send(c, o.useCase.Wishlist(id))
+}
+
+func (o *orderManagementHandler) HandleMoveWishlist(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid id parameter",
+		})
+		return
+	}
+
+	response := o.useCase.MoveWishlistToOrder(id)
+	if response != nil {
+		utils.Send(c, response)
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
+
+func (o *orderManagementHandler) handleWishlistAction(c *gin.Context, callback func(api.OrderActionRequest) api.Response) {
+	request, err := parseRequestBody(c)
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		})
+		return
+	}
+
+	response := callback(request)
+	if response != nil {
+		utils.Send(c, response)
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
+
 func parseRequestBody(c *gin.Context) (api.OrderActionRequest, error) {
 	var request api.OrderActionRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
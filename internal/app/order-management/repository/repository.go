@@ -2,33 +2,33 @@ package repository
 
 import (
 	"context"
-	"errors"
-	"log"
 
 	"github.com/allnightmarel0Ng/albums/internal/domain/model"
 	"github.com/allnightmarel0Ng/albums/internal/domain/repository"
 )
 
-var (
-	ErrDatabaseCommunication = errors.New("db communication error")
-	ErrAlreadyInOrder        = errors.New("album is already in order")
-	ErrNotInOrder            = errors.New("album not in order")
-	ErrNoOrderFound          = errors.New("album not found")
-)
-
 type OrderManagementRepository interface {
 	AddToOrder(ctx context.Context, userID, albumID int) error
 	RemoveFromOrder(ctx context.Context, userID, albumID int) error
 	UserOrder(ctx context.Context, userID int, unpaidOnly bool) ([]model.Order, error)
+	CancelOrder(ctx context.Context, userID int) error
+	AddToWishlist(ctx context.Context, userID, albumID int) error
+	RemoveFromWishlist(ctx context.Context, userID, albumID int) error
+	Wishlist(ctx context.Context, userID int) ([]model.Album, error)
+	MoveWishlistToOrder(ctx context.Context, userID int) error
 }
 
 type orderManagementRepository struct {
-	orders repository.OrderRepository
+	orders   repository.OrderRepository
+	wishlist repository.WishlistRepository
+	albums   repository.AlbumRepository
 }
 
-func NewOrderManagementRepository(orders repository.OrderRepository) OrderManagementRepository {
+func NewOrderManagementRepository(orders repository.OrderRepository, wishlist repository.WishlistRepository, albums repository.AlbumRepository) OrderManagementRepository {
 	return &orderManagementRepository{
-		orders: orders,
+		orders:   orders,
+		wishlist: wishlist,
+		albums:   albums,
 	}
 }
 
@@ -37,20 +37,6 @@ func (o *orderManagementRepository) AddToOrder(ctx context.Context, userID, albu
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		orders, err := o.UserOrder(ctx, userID, true)
-		if err != nil {
-			return ErrDatabaseCommunication
-		}
-
-		log.Print(orders)
-		if len(orders) == 1 {
-			for i := 0; i < len(orders[0].Albums); i++ {
-				if albumID == orders[0].Albums[i].ID {
-					return ErrAlreadyInOrder
-				}
-			}
-		}
-
 		return o.orders.AddAlbumToUserOrder(ctx, userID, albumID)
 	}
 }
@@ -60,37 +46,66 @@ func (o *orderManagementRepository) RemoveFromOrder(ctx context.Context, userID,
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		orders, err := o.UserOrder(ctx, userID, true)
-		if err != nil {
-			return ErrDatabaseCommunication
-		}
+		return o.orders.DeleteAlbumFromUserOrder(ctx, userID, albumID)
+	}
+}
 
-		if len(orders) == 0 {
-			return ErrNoOrderFound
-		}
+func (o *orderManagementRepository) UserOrder(ctx context.Context, userID int, unpaidOnly bool) ([]model.Order, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		return o.orders.GetUserOrders(ctx, userID, unpaidOnly)
+	}
+}
 
-		if len(orders) == 1 {
-			found := false
-			for i := 0; i < len(orders[0].Albums); i++ {
-				if albumID == orders[0].Albums[i].ID {
-					found = true
-				}
-			}
+func (o *orderManagementRepository) CancelOrder(ctx context.Context, userID int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		_, err := o.orders.CancelUnpaidOrder(ctx, userID)
+		return err
+	}
+}
 
-			if !found {
-				return ErrNotInOrder
-			}
-		}
+func (o *orderManagementRepository) AddToWishlist(ctx context.Context, userID, albumID int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return o.wishlist.AddToWishlist(ctx, userID, albumID)
+	}
+}
 
-		return o.orders.DeleteAlbumFromUserOrder(ctx, userID, albumID)
+func (o *orderManagementRepository) RemoveFromWishlist(ctx context.Context, userID, albumID int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return o.wishlist.RemoveFromWishlist(ctx, userID, albumID)
 	}
 }
 
-func (o *orderManagementRepository) UserOrder(ctx context.Context, userID int, unpaidOnly bool) ([]model.Order, error) {
+func (o *orderManagementRepository) Wishlist(ctx context.Context, userID int) ([]model.Album, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		return o.orders.GetUserOrders(ctx, userID, unpaidOnly)
+		ids, err := o.wishlist.GetWishlistAlbumIDs(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		return o.albums.GetAlbumsByIDs(ctx, ids)
+	}
+}
+
+func (o *orderManagementRepository) MoveWishlistToOrder(ctx context.Context, userID int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return o.wishlist.MoveWishlistToOrder(ctx, userID)
 	}
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/allnightmarel0Ng/albums/internal/app/order-management/repository"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	domainRepository "github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
 )
 
@@ -14,6 +15,11 @@ type OrderManagementUseCase interface {
 	AddAlbumToUserOrder(request api.OrderActionRequest) api.Response
 	RemoveAlbumFromUserOrder(request api.OrderActionRequest) api.Response
 	UserOrder(userID int, unpaidOnly bool) api.Response
+	CancelOrder(userID int) api.Response
+	AddAlbumToWishlist(request api.OrderActionRequest) api.Response
+	RemoveAlbumFromWishlist(request api.OrderActionRequest) api.Response
+	Wishlist(userID int) api.Response
+	MoveWishlistToOrder(userID int) api.Response
 }
 
 type orderManagementUseCase struct {
@@ -30,49 +36,14 @@ func (o *orderManagementUseCase) AddAlbumToUserOrder(request api.OrderActionRequ
 	ctx, cancel := utils.DeadlineContext(10)
 	defer cancel()
 
-	err := o.repo.AddToOrder(ctx, request.UserID, request.AlbumID)
-	if err != nil {
-		log.Print(err.Error())
-		switch err {
-		case context.DeadlineExceeded:
-		case repository.ErrDatabaseCommunication:
-			return &api.ErrorResponse{
-				Code:  http.StatusInternalServerError,
-				Error: "database fail",
-			}
-		default:
-			return &api.ErrorResponse{
-				Code:  http.StatusBadRequest,
-				Error: err.Error(),
-			}
-		}
-	}
-
-	return nil
+	return orderError(o.repo.AddToOrder(ctx, request.UserID, request.AlbumID))
 }
 
 func (o *orderManagementUseCase) RemoveAlbumFromUserOrder(request api.OrderActionRequest) api.Response {
 	ctx, cancel := utils.DeadlineContext(10)
 	defer cancel()
 
-	err := o.repo.RemoveFromOrder(ctx, request.UserID, request.AlbumID)
-	if err != nil {
-		switch err {
-		case context.DeadlineExceeded:
-		case repository.ErrDatabaseCommunication:
-			return &api.ErrorResponse{
-				Code:  http.StatusInternalServerError,
-				Error: "database fail",
-			}
-		default:
-			return &api.ErrorResponse{
-				Code:  http.StatusBadRequest,
-				Error: err.Error(),
-			}
-		}
-	}
-
-	return nil
+	return orderError(o.repo.RemoveFromOrder(ctx, request.UserID, request.AlbumID))
 }
 
 func (o *orderManagementUseCase) UserOrder(userID int, unpaidOnly bool) api.Response {
@@ -107,3 +78,89 @@ func (o *orderManagementUseCase) UserOrder(userID int, unpaidOnly bool) api.Resp
 		Orders: result,
 	}
 }
+
+func (o *orderManagementUseCase) CancelOrder(userID int) api.Response {
+	ctx, cancel := utils.DeadlineContext(10)
+	defer cancel()
+
+	err := o.repo.CancelOrder(ctx, userID)
+	if err == domainRepository.ErrOrderNotFound {
+		return &api.ErrorResponse{
+			Code:  http.StatusNotFound,
+			Error: "no unpaid order found",
+		}
+	}
+
+	return orderError(err)
+}
+
+func (o *orderManagementUseCase) AddAlbumToWishlist(request api.OrderActionRequest) api.Response {
+	ctx, cancel := utils.DeadlineContext(10)
+	defer cancel()
+
+	return orderError(o.repo.AddToWishlist(ctx, request.UserID, request.AlbumID))
+}
+
+func (o *orderManagementUseCase) RemoveAlbumFromWishlist(request api.OrderActionRequest) api.Response {
+	ctx, cancel := utils.DeadlineContext(10)
+	defer cancel()
+
+	return orderError(o.repo.RemoveFromWishlist(ctx, request.UserID, request.AlbumID))
+}
+
+func (o *orderManagementUseCase) Wishlist(userID int) api.Response {
+	ctx, cancel := utils.DeadlineContext(10)
+	defer cancel()
+
+	albums, err := o.repo.Wishlist(ctx, userID)
+	if err != nil {
+		log.Print(err.Error())
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "error retrieving wishlist from database",
+		}
+	}
+
+	return &api.WishlistResponse{
+		Code:   http.StatusOK,
+		Albums: albums,
+	}
+}
+
+func (o *orderManagementUseCase) MoveWishlistToOrder(userID int) api.Response {
+	ctx, cancel := utils.DeadlineContext(10)
+	defer cancel()
+
+	return orderError(o.repo.MoveWishlistToOrder(ctx, userID))
+}
+
+// orderError picks the status for the errors of order and wishlist changes
+func orderError(err error) api.Response {
+	switch err {
+	case nil:
+		return nil
+	case domainRepository.ErrAlbumNotFound, domainRepository.ErrOrderNotFound,
+		domainRepository.ErrNotInOrder, domainRepository.ErrNotInWishlist:
+		return &api.ErrorResponse{
+			Code:  http.StatusNotFound,
+			Error: err.Error(),
+		}
+	case domainRepository.ErrAlbumPurchased, domainRepository.ErrAlreadyInOrder,
+		domainRepository.ErrAlreadyInWishlist, domainRepository.ErrCurrencyMismatch:
+		return &api.ErrorResponse{
+			Code:  http.StatusConflict,
+			Error: err.Error(),
+		}
+	case context.DeadlineExceeded:
+		return &api.ErrorResponse{
+			Code:  http.StatusGatewayTimeout,
+			Error: "database timeout",
+		}
+	default:
+		log.Print(err.Error())
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "database fail",
+		}
+	}
+}
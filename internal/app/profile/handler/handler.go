@@ -2,6 +2,8 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/allnightmarel0Ng/albums/internal/app/profile/usecase"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
@@ -14,6 +16,9 @@ type ProfileHandler interface {
 	HandleUserProfile(c *gin.Context)
 	HandleAlbumProfile(c *gin.Context)
 	HandleOwners(c *gin.Context)
+	HandleTransactions(c *gin.Context)
+	HandleExchangeRates(c *gin.Context)
+	HandleChangeCurrency(c *gin.Context)
 }
 
 type profileHandler struct {
@@ -66,6 +71,63 @@ func (p *profileHandler) HandleOwners(c *gin.Context) {
 	utils.Send(c, p.useCase.GetAlbumOwnersIds(id))
 }
 
+func (p *profileHandler) HandleTransactions(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		sendParsingError(c, err)
+		return
+	}
+
+	pageNumber, err := strconv.ParseUint(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || pageNumber == 0 {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid 'page' parameter",
+		})
+		return
+	}
+
+	pageSize, err := strconv.ParseUint(c.DefaultQuery("pageSize", "10"), 10, 64)
+	if err != nil || pageSize == 0 {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid 'pageSize' parameter",
+		})
+		return
+	}
+
+	utils.Send(c, p.useCase.GetTransactions(id, uint(pageNumber), uint(pageSize)))
+}
+
+func (p *profileHandler) HandleExchangeRates(c *gin.Context) {
+	utils.Send(c, p.useCase.GetExchangeRates())
+}
+
+func (p *profileHandler) HandleChangeCurrency(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		sendParsingError(c, err)
+		return
+	}
+
+	var request api.CurrencyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid request body",
+		})
+		return
+	}
+
+	response := p.useCase.ChangeCurrency(id, strings.ToUpper(request.Currency))
+	if response != nil {
+		utils.Send(c, response)
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
+
 func sendParsingError(c *gin.Context, err error) {
 	utils.Send(c, &api.ErrorResponse{
 		Code:  http.StatusBadRequest,
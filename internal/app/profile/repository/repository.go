@@ -12,19 +12,26 @@ type ProfileRepository interface {
 	GetArtistProfile(ctx context.Context, id int) (model.Artist, []model.Album, error)
 	GetAlbumProfile(ctx context.Context, id int) (model.Album, error)
 	GetAlbumOwnersIds(ctx context.Context, albumId int) (string, []int, error)
+	GetTransactionsAndCount(ctx context.Context, userID int, offset, limit uint) (uint, []model.LedgerEntry, error)
+	GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error)
+	ChangeCurrency(ctx context.Context, userID int, currency string) error
 }
 
 type profileRepository struct {
 	users   repository.UserRepository
 	albums  repository.AlbumRepository
 	artists repository.ArtistRepository
+	ledger  repository.LedgerRepository
+	rates   repository.ExchangeRateRepository
 }
 
-func NewProfileRepository(users repository.UserRepository, albums repository.AlbumRepository, artists repository.ArtistRepository) ProfileRepository {
+func NewProfileRepository(users repository.UserRepository, albums repository.AlbumRepository, artists repository.ArtistRepository, ledger repository.LedgerRepository, rates repository.ExchangeRateRepository) ProfileRepository {
 	return &profileRepository{
 		users:   users,
 		albums:  albums,
 		artists: artists,
+		ledger:  ledger,
+		rates:   rates,
 	}
 }
 
@@ -93,3 +100,41 @@ func (p *profileRepository) GetAlbumOwnersIds(ctx context.Context, albumID int)
 		return name, ids, err
 	}
 }
+
+func (p *profileRepository) GetTransactionsAndCount(ctx context.Context, userID int, offset, limit uint) (uint, []model.LedgerEntry, error) {
+	select {
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	default:
+		count, err := p.ledger.GetLedgerEntriesCount(ctx, userID)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		entries, err := p.ledger.GetLedgerEntries(ctx, userID, offset, limit)
+		return count, entries, err
+	}
+}
+
+func (p *profileRepository) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		return p.rates.GetExchangeRates(ctx)
+	}
+}
+
+func (p *profileRepository) ChangeCurrency(ctx context.Context, userID int, currency string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		_, err := p.rates.GetExchangeRate(ctx, currency)
+		if err != nil {
+			return err
+		}
+
+		return p.users.ChangeCurrency(ctx, userID, currency)
+	}
+}
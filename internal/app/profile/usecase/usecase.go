@@ -7,6 +7,8 @@ import (
 
 	"github.com/allnightmarel0Ng/albums/internal/app/profile/repository"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	domainRepository "github.com/allnightmarel0Ng/albums/internal/domain/repository"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
 )
 
@@ -15,6 +17,9 @@ type ProfileUseCase interface {
 	GetArtistProfile(id int) api.Response
 	GetAlbumProfile(id int) api.Response
 	GetAlbumOwnersIds(albumID int) api.Response
+	GetTransactions(userID int, pageNumber, pageSize uint) api.Response
+	GetExchangeRates() api.Response
+	ChangeCurrency(userID int, currency string) api.Response
 }
 
 type profileUseCase struct {
@@ -134,3 +139,72 @@ func (p *profileUseCase) GetAlbumOwnersIds(albumID int) api.Response {
 		AlbumName: name,
 	}
 }
+
+func (p *profileUseCase) GetTransactions(userID int, pageNumber, pageSize uint) api.Response {
+	offset := (pageNumber - 1) * pageSize
+	limit := pageSize
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	count, entries, err := p.repo.GetTransactionsAndCount(ctx, userID, offset, limit)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "database communication error",
+		}
+	}
+
+	return &api.TransactionsResponse{
+		Code:         http.StatusOK,
+		Transactions: entries,
+		Count:        count,
+	}
+}
+
+func (p *profileUseCase) GetExchangeRates() api.Response {
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	rates, err := p.repo.GetExchangeRates(ctx)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "database communication error",
+		}
+	}
+
+	return &api.ExchangeRatesResponse{
+		Code:  http.StatusOK,
+		Rates: rates,
+	}
+}
+
+func (p *profileUseCase) ChangeCurrency(userID int, currency string) api.Response {
+	if !model.IsValidCurrency(currency) {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: model.ErrInvalidCurrency.Error(),
+		}
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	err := p.repo.ChangeCurrency(ctx, userID, currency)
+	switch err {
+	case nil:
+		return nil
+	case domainRepository.ErrUnknownCurrency:
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "no exchange rate for such currency",
+		}
+	default:
+		log.Printf("unable to change currency: %s", err.Error())
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "database communication error",
+		}
+	}
+}
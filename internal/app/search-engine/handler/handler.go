@@ -12,6 +12,10 @@ import (
 type SearchEngineHandler interface {
 	HandleSearch(c *gin.Context)
 	HandleRandom(c *gin.Context)
+	HandleRecommendations(c *gin.Context)
+	HandleSuggest(c *gin.Context)
+	HandleChart(c *gin.Context)
+	HandleArtistChartPositions(c *gin.Context)
 }
 
 type searchEngineHandler struct {
@@ -27,7 +31,7 @@ func NewSearchEngineHandler(useCase usecase.SearchEngineUseCase) SearchEngineHan
 func (s *searchEngineHandler) HandleSearch(c *gin.Context) {
 	var request api.SearchRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
+	if err := c.ShouldBind(&request); err != nil {
 		utils.Send(c, &api.ErrorResponse{
 			Code:  http.StatusBadRequest,
 			Error: "invalid search request",
@@ -35,7 +39,7 @@ func (s *searchEngineHandler) HandleSearch(c *gin.Context) {
 		return
 	}
 
-	utils.Send(c, s.useCase.SearchEntities(request.Query))
+	utils.Send(c, s.useCase.SearchEntities(request))
 }
 
 func (s *searchEngineHandler) HandleRandom(c *gin.Context) {
@@ -50,3 +54,55 @@ func (s *searchEngineHandler) HandleRandom(c *gin.Context) {
 
 	utils.Send(c, s.useCase.RandomEntities(request.ArtistsCount, request.AlbumsCount))
 }
+
+func (s *searchEngineHandler) HandleRecommendations(c *gin.Context) {
+	var request api.RecommendationsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid recommendations request",
+		})
+		return
+	}
+
+	utils.Send(c, s.useCase.Recommendations(request.UserID, request.ArtistsCount, request.AlbumsCount))
+}
+
+func (s *searchEngineHandler) HandleSuggest(c *gin.Context) {
+	var request api.SuggestRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid suggest request",
+		})
+		return
+	}
+
+	utils.Send(c, s.useCase.Suggest(request.Query, request.Limit))
+}
+
+func (s *searchEngineHandler) HandleChart(c *gin.Context) {
+	var request api.ChartRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid chart request",
+		})
+		return
+	}
+
+	utils.Send(c, s.useCase.Chart(request))
+}
+
+func (s *searchEngineHandler) HandleArtistChartPositions(c *gin.Context) {
+	id, err := utils.GetParam(c, "id")
+	if err != nil {
+		utils.Send(c, &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid artist id",
+		})
+		return
+	}
+
+	utils.Send(c, s.useCase.ArtistChartPositions(id))
+}
@@ -9,18 +9,33 @@ import (
 
 type SearchEngineRepository interface {
 	GetRandomNEntities(ctx context.Context, artistsCount uint, albumsCount uint) ([]model.Artist, []model.Album, error)
-	GetEntitiesLikeName(ctx context.Context, name string) ([]model.Artist, []model.Album, error)
+	SearchEntities(ctx context.Context, params model.SearchParams) ([]model.Artist, []model.Album, []model.SearchMatch, error)
+	Suggest(ctx context.Context, prefix string, limit uint) ([]model.Suggestion, error)
+	RefreshSuggestions(ctx context.Context) error
+	GetRecommendations(ctx context.Context, userID int, artistsCount, albumsCount uint) ([]model.Artist, []model.Album, []model.Recommendation, error)
+	RefreshCharts(ctx context.Context) error
+	RefreshCoPurchases(ctx context.Context) error
+	GetChart(ctx context.Context, period, entity, genre string, limit uint) ([]model.ChartEntry, error)
+	GetArtistChartPositions(ctx context.Context, artistID int) ([]model.ChartPosition, error)
 }
 
 type searchEngineRepository struct {
-	artists repository.ArtistRepository
-	albums  repository.AlbumRepository
+	artists         repository.ArtistRepository
+	albums          repository.AlbumRepository
+	search          repository.SearchRepository
+	recommendations repository.RecommendationRepository
+	charts          repository.ChartRepository
+	suggestions     *suggestionIndex
 }
 
-func NewSearchEngineRepository(artists repository.ArtistRepository, albums repository.AlbumRepository) SearchEngineRepository {
+func NewSearchEngineRepository(artists repository.ArtistRepository, albums repository.AlbumRepository, search repository.SearchRepository, recommendations repository.RecommendationRepository, charts repository.ChartRepository) SearchEngineRepository {
 	return &searchEngineRepository{
-		artists: artists,
-		albums:  albums,
+		artists:         artists,
+		albums:          albums,
+		search:          search,
+		recommendations: recommendations,
+		charts:          charts,
+		suggestions:     newSuggestionIndex(),
 	}
 }
 
@@ -39,17 +54,249 @@ func (s *searchEngineRepository) GetRandomNEntities(ctx context.Context, artists
 	}
 }
 
-func (s *searchEngineRepository) GetEntitiesLikeName(ctx context.Context, name string) ([]model.Artist, []model.Album, error) {
+// SearchEntities returns the found artists and albums in the order the params
+// ask for.
+func (s *searchEngineRepository) SearchEntities(ctx context.Context, params model.SearchParams) ([]model.Artist, []model.Album, []model.SearchMatch, error) {
 	select {
 	case <-ctx.Done():
-		return nil, nil, ctx.Err()
+		return nil, nil, nil, ctx.Err()
 	default:
-		artists, err := s.artists.GetArtistsLikeName(ctx, name)
+		matches, err := s.search.Search(ctx, params)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
-		albums, err := s.albums.GetAlbumsLikeName(ctx, name)
-		return artists, albums, err
+		var artistIDs, albumIDs []int
+		for _, match := range matches {
+			switch match.Entity {
+			case model.SearchEntityArtist:
+				artistIDs = append(artistIDs, match.ID)
+			case model.SearchEntityAlbum:
+				albumIDs = append(albumIDs, match.ID)
+			}
+		}
+
+		artists, err := s.artists.GetArtistsByIDs(ctx, artistIDs)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		albums, err := s.albums.GetAlbumsByIDs(ctx, albumIDs)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return orderByIDs(artists, artistIDs, func(artist model.Artist) int { return artist.ID }),
+			orderByIDs(albums, albumIDs, func(album model.Album) int { return album.ID }),
+			matches, nil
+	}
+}
+
+// Suggest looks the prefix up in the in-memory index, which is as fresh as the
+// last RefreshSuggestions.
+func (s *searchEngineRepository) Suggest(ctx context.Context, prefix string, limit uint) ([]model.Suggestion, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		return s.suggestions.Lookup(prefix, limit), nil
+	}
+}
+
+func (s *searchEngineRepository) RefreshSuggestions(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		suggestions, err := s.search.GetSuggestions(ctx)
+		if err != nil {
+			return err
+		}
+
+		s.suggestions.Replace(suggestions)
+		return nil
+	}
+}
+
+// GetRecommendations returns personal recommendations for the user topped up
+// with popular artists and albums, anonymous users (userID 0) get only the
+// popular ones.
+func (s *searchEngineRepository) GetRecommendations(ctx context.Context, userID int, artistsCount, albumsCount uint) ([]model.Artist, []model.Album, []model.Recommendation, error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, nil, ctx.Err()
+	default:
+		var artistRecommendations, albumRecommendations []model.Recommendation
+		var err error
+
+		if userID != 0 {
+			artistRecommendations, err = s.recommendations.RecommendArtists(ctx, userID, artistsCount)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			albumRecommendations, err = s.recommendations.RecommendAlbums(ctx, userID, albumsCount)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		}
+
+		if uint(len(artistRecommendations)) < artistsCount {
+			popular, err := s.recommendations.PopularArtists(ctx, artistsCount+uint(len(artistRecommendations)))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			artistRecommendations = topUp(artistRecommendations, popular, artistsCount)
+		}
+
+		if uint(len(albumRecommendations)) < albumsCount {
+			popular, err := s.recommendations.PopularAlbums(ctx, userID, albumsCount+uint(len(albumRecommendations)))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			albumRecommendations = topUp(albumRecommendations, popular, albumsCount)
+		}
+
+		artistIDs := recommendedIDs(artistRecommendations)
+		artists, err := s.artists.GetArtistsByIDs(ctx, artistIDs)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		albumIDs := recommendedIDs(albumRecommendations)
+		albums, err := s.albums.GetAlbumsByIDs(ctx, albumIDs)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return orderByIDs(artists, artistIDs, func(artist model.Artist) int { return artist.ID }),
+			orderByIDs(albums, albumIDs, func(album model.Album) int { return album.ID }),
+			append(artistRecommendations, albumRecommendations...), nil
+	}
+}
+
+func (s *searchEngineRepository) RefreshCharts(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return s.charts.RefreshCharts(ctx)
+	}
+}
+
+func (s *searchEngineRepository) RefreshCoPurchases(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return s.recommendations.RefreshCoPurchases(ctx)
+	}
+}
+
+// GetChart returns the chart entries with their artists or albums filled in.
+func (s *searchEngineRepository) GetChart(ctx context.Context, period, entity, genre string, limit uint) ([]model.ChartEntry, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		entries, err := s.charts.GetChart(ctx, period, entity, genre, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]int, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+
+		result := make([]model.ChartEntry, 0, len(entries))
+		switch entity {
+		case model.SearchEntityArtist:
+			artists, err := s.artists.GetArtistsByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+
+			byID := make(map[int]model.Artist, len(artists))
+			for _, artist := range artists {
+				byID[artist.ID] = artist
+			}
+			for _, entry := range entries {
+				if artist, ok := byID[entry.ID]; ok {
+					entry.Artist = &artist
+					result = append(result, entry)
+				}
+			}
+		case model.SearchEntityAlbum:
+			albums, err := s.albums.GetAlbumsByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+
+			byID := make(map[int]model.Album, len(albums))
+			for _, album := range albums {
+				byID[album.ID] = album
+			}
+			for _, entry := range entries {
+				if album, ok := byID[entry.ID]; ok {
+					entry.Album = &album
+					result = append(result, entry)
+				}
+			}
+		}
+
+		return result, nil
+	}
+}
+
+func (s *searchEngineRepository) GetArtistChartPositions(ctx context.Context, artistID int) ([]model.ChartPosition, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		return s.charts.GetArtistChartPositions(ctx, artistID)
+	}
+}
+
+// topUp appends the extra recommendations that aren't in the list yet until
+// the list has count of them.
+func topUp(recommendations, extra []model.Recommendation, count uint) []model.Recommendation {
+	seen := make(map[int]bool, len(recommendations))
+	for _, recommendation := range recommendations {
+		seen[recommendation.ID] = true
+	}
+
+	for _, recommendation := range extra {
+		if uint(len(recommendations)) >= count {
+			break
+		}
+		if !seen[recommendation.ID] {
+			seen[recommendation.ID] = true
+			recommendations = append(recommendations, recommendation)
+		}
+	}
+	return recommendations
+}
+
+func recommendedIDs(recommendations []model.Recommendation) []int {
+	ids := make([]int, len(recommendations))
+	for i, recommendation := range recommendations {
+		ids[i] = recommendation.ID
+	}
+	return ids
+}
+
+func orderByIDs[T any](entities []T, ids []int, id func(T) int) []T {
+	byID := make(map[int]T, len(entities))
+	for _, entity := range entities {
+		byID[id(entity)] = entity
+	}
+
+	result := make([]T, 0, len(ids))
+	for _, current := range ids {
+		if entity, ok := byID[current]; ok {
+			result = append(result, entity)
+		}
 	}
+	return result
 }
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+)
+
+// maxScannedSuggestions bounds the work of a single lookup, short prefixes
+// match a good part of the catalog.
+const maxScannedSuggestions = 1000
+
+type suggestionEntry struct {
+	key string
+	// word is the number of the word the key starts at, so that names that
+	// start with the prefix go before names that only contain it
+	word       int
+	suggestion model.Suggestion
+}
+
+// suggestionIndex is a sorted list of every name and one of every ending of a
+// name that starts at a word, "the dark side" is indexed as "the dark side"
+// in the former and "dark side" and "side" in the latter. A prefix lookup is
+// a binary search in each, the names go first, so the scan bound never
+// leaves out a name that starts with the prefix for the ones that contain it.
+type suggestionIndex struct {
+	mu    sync.RWMutex
+	names []suggestionEntry
+	words []suggestionEntry
+}
+
+func newSuggestionIndex() *suggestionIndex {
+	return &suggestionIndex{}
+}
+
+func (s *suggestionIndex) Replace(suggestions []model.Suggestion) {
+	names := make([]suggestionEntry, 0, len(suggestions))
+	words := make([]suggestionEntry, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		name := strings.ToLower(suggestion.Name)
+		for word, start := range wordStarts(name) {
+			entry := suggestionEntry{
+				key:        name[start:],
+				word:       word,
+				suggestion: suggestion,
+			}
+			if word == 0 {
+				names = append(names, entry)
+			} else {
+				words = append(words, entry)
+			}
+		}
+	}
+
+	sortByKey(names)
+	sortByKey(words)
+
+	s.mu.Lock()
+	s.names = names
+	s.words = words
+	s.mu.Unlock()
+}
+
+func (s *suggestionIndex) Lookup(prefix string, limit uint) []model.Suggestion {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	result := make([]model.Suggestion, 0, limit)
+	if prefix == "" || limit == 0 {
+		return result
+	}
+
+	s.mu.RLock()
+	found := scanPrefix(nil, s.names, prefix)
+	found = scanPrefix(found, s.words, prefix)
+	s.mu.RUnlock()
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].word != found[j].word {
+			return found[i].word < found[j].word
+		}
+		if len(found[i].suggestion.Name) != len(found[j].suggestion.Name) {
+			return len(found[i].suggestion.Name) < len(found[j].suggestion.Name)
+		}
+		return found[i].suggestion.Name < found[j].suggestion.Name
+	})
+
+	type entity struct {
+		kind string
+		id   int
+	}
+	seen := make(map[entity]bool)
+	for _, entry := range found {
+		key := entity{entry.suggestion.Kind, entry.suggestion.ID}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		result = append(result, entry.suggestion)
+		if uint(len(result)) == limit {
+			break
+		}
+	}
+	return result
+}
+
+func sortByKey(entries []suggestionEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+}
+
+// scanPrefix appends the entries that start with the prefix to found, until
+// found holds maxScannedSuggestions of them.
+func scanPrefix(found []suggestionEntry, entries []suggestionEntry, prefix string) []suggestionEntry {
+	first := sort.Search(len(entries), func(i int) bool {
+		return entries[i].key >= prefix
+	})
+
+	for i := first; i < len(entries) && len(found) < maxScannedSuggestions; i++ {
+		if !strings.HasPrefix(entries[i].key, prefix) {
+			break
+		}
+		found = append(found, entries[i])
+	}
+	return found
+}
+
+// wordStarts returns the byte offsets at which the words of the name start.
+func wordStarts(name string) []int {
+	var starts []int
+	inWord := false
+	for i, r := range name {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWordRune && !inWord {
+			starts = append(starts, i)
+		}
+		inWord = isWordRune
+	}
+	return starts
+}
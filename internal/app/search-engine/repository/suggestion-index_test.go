@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+)
+
+func TestSuggestionIndexRanksNamesBeforeWords(t *testing.T) {
+	index := newSuggestionIndex()
+	index.Replace([]model.Suggestion{
+		{Kind: "album", ID: 1, Name: "The Dark Side of the Moon"},
+		{Kind: "track", ID: 2, Name: "Darkness", AlbumID: 1},
+		{Kind: "artist", ID: 3, Name: "Dark"},
+		{Kind: "track", ID: 4, Name: "Into the Dark", AlbumID: 1},
+		{Kind: "track", ID: 5, Name: "Bright", AlbumID: 1},
+	})
+
+	got := names(index.Lookup(" DARK", 10))
+	want := []string{"Dark", "Darkness", "The Dark Side of the Moon", "Into the Dark"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got = names(index.Lookup("dark", 2))
+	if !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("got %v with the limit, want %v", got, want[:2])
+	}
+}
+
+func TestSuggestionIndexDeduplicates(t *testing.T) {
+	index := newSuggestionIndex()
+	index.Replace([]model.Suggestion{
+		{Kind: "track", ID: 1, Name: "Side by Side"},
+		{Kind: "album", ID: 1, Name: "Side by Side"},
+	})
+
+	got := index.Lookup("side", 10)
+	want := []model.Suggestion{
+		{Kind: "track", ID: 1, Name: "Side by Side"},
+		{Kind: "album", ID: 1, Name: "Side by Side"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSuggestionIndexBoundsScanAfterRanking(t *testing.T) {
+	suggestions := make([]model.Suggestion, 0, maxScannedSuggestions+1)
+	for i := 0; i < maxScannedSuggestions; i++ {
+		// "aa0000" and so on sort before "azure"
+		suggestions = append(suggestions, model.Suggestion{Kind: "track", ID: i + 1, Name: fmt.Sprintf("x aa%04d", i)})
+	}
+	suggestions = append(suggestions, model.Suggestion{Kind: "album", ID: 1, Name: "Azure"})
+
+	index := newSuggestionIndex()
+	index.Replace(suggestions)
+
+	got := index.Lookup("a", 1)
+	if len(got) != 1 || got[0].Name != "Azure" {
+		t.Errorf("got %+v, want the name that starts with the prefix", got)
+	}
+}
+
+func names(suggestions []model.Suggestion) []string {
+	result := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		result = append(result, suggestion.Name)
+	}
+	return result
+}
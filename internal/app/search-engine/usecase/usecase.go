@@ -1,35 +1,94 @@
 package usecase
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/allnightmarel0Ng/albums/internal/app/search-engine/repository"
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/kafka"
 	"github.com/allnightmarel0Ng/albums/internal/utils"
 )
 
 type SearchEngineUseCase interface {
-	SearchEntities(query string) api.Response
+	SearchEntities(request api.SearchRequest) api.Response
 	RandomEntities(artistsCount, albumsCount uint) api.Response
+	Recommendations(userID int, artistsCount, albumsCount uint) api.Response
+	Suggest(prefix string, limit uint) api.Response
+	KeepSuggestionsFresh()
+	RefreshCoPurchasesEternally()
+	Chart(request api.ChartRequest) api.Response
+	ArtistChartPositions(artistID int) api.Response
+	RefreshChartsEternally()
 }
 
+// Page sizes of a search, artists and albums counted together.
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// maxRecommendations is the maximum number of artists, as well as of albums,
+// on the main page.
+const maxRecommendations = 50
+
+// coPurchasesRefreshInterval is how often the co-purchases are recomputed from
+// the purchases, so the recommendations lag behind them at most by that much.
+const coPurchasesRefreshInterval = 5 * time.Minute
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 20
+
+	// suggestionsRefreshInterval is how often the suggestions are reloaded even
+	// if no catalog change has been announced, in case a message got lost.
+	suggestionsRefreshInterval = 10 * time.Minute
+)
+
+const (
+	defaultChartLimit = 10
+	maxChartLimit     = 100
+
+	// chartsRefreshInterval is how often the charts are recomputed from the
+	// buy logs, so they lag behind the purchases at most by that much.
+	chartsRefreshInterval = 5 * time.Minute
+)
+
 type searchEngineUseCase struct {
-	repo repository.SearchEngineRepository
+	repo     repository.SearchEngineRepository
+	consumer *kafka.Consumer
 }
 
-func NewSearchEngineUseCase(repo repository.SearchEngineRepository) SearchEngineUseCase {
+func NewSearchEngineUseCase(repo repository.SearchEngineRepository, consumer *kafka.Consumer) SearchEngineUseCase {
 	return &searchEngineUseCase{
-		repo: repo,
+		repo:     repo,
+		consumer: consumer,
 	}
 }
 
-func (s *searchEngineUseCase) SearchEntities(query string) api.Response {
-	query = utils.SearchLikeString(query)
+func (s *searchEngineUseCase) SearchEntities(request api.SearchRequest) api.Response {
+	params, err := searchParams(request)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: err.Error(),
+		}
+	}
+
+	limit := params.Limit
+	// one more result tells whether there is a next page
+	params.Limit++
 
 	ctx, cancel := utils.DeadlineContext(5)
 	defer cancel()
 
-	artists, albums, err := s.repo.GetEntitiesLikeName(ctx, query)
+	artists, albums, matches, err := s.repo.SearchEntities(ctx, params)
 	if err != nil {
 		return &api.ErrorResponse{
 			Code:  http.StatusInternalServerError,
@@ -37,11 +96,297 @@ func (s *searchEngineUseCase) SearchEntities(query string) api.Response {
 		}
 	}
 
-	return &api.SearchEngineResponse{
+	response := &api.SearchEngineResponse{
 		Code:    http.StatusOK,
 		Artists: artists,
 		Albums:  albums,
+		Matches: matches,
+	}
+
+	if uint(len(matches)) > limit {
+		last := matches[limit-1]
+		response.Matches = matches[:limit]
+		response.Artists, response.Albums = withoutLast(artists, albums, matches[limit])
+		response.NextCursor = encodeCursor(model.SearchCursor{
+			Sort:    params.Sort,
+			SortKey: last.SortKey,
+			Entity:  last.Entity,
+			ID:      last.ID,
+		})
+	}
+
+	return response
+}
+
+func (s *searchEngineUseCase) Recommendations(userID int, artistsCount, albumsCount uint) api.Response {
+	artistsCount = min(artistsCount, maxRecommendations)
+	albumsCount = min(albumsCount, maxRecommendations)
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	artists, albums, recommendations, err := s.repo.GetRecommendations(ctx, userID, artistsCount, albumsCount)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db unexpected error",
+		}
+	}
+
+	return &api.SearchEngineResponse{
+		Code:            http.StatusOK,
+		Artists:         artists,
+		Albums:          albums,
+		Recommendations: recommendations,
+	}
+}
+
+func (s *searchEngineUseCase) Suggest(prefix string, limit uint) api.Response {
+	switch {
+	case limit == 0:
+		limit = defaultSuggestLimit
+	case limit > maxSuggestLimit:
+		limit = maxSuggestLimit
 	}
+
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	suggestions, err := s.repo.Suggest(ctx, prefix, limit)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "unable to get suggestions",
+		}
+	}
+
+	return &api.SuggestionsResponse{
+		Code:        http.StatusOK,
+		Suggestions: suggestions,
+	}
+}
+
+// KeepSuggestionsFresh loads the suggestions and reloads them on every catalog
+// change and every suggestionsRefreshInterval. It never returns.
+func (s *searchEngineUseCase) KeepSuggestionsFresh() {
+	s.refreshSuggestions()
+
+	go func() {
+		for range time.Tick(suggestionsRefreshInterval) {
+			s.refreshSuggestions()
+		}
+	}()
+
+	s.consumer.ConsumeMessagesEternally(func(msg []byte) error {
+		var message api.CatalogKafkaMessage
+		if err := json.Unmarshal(msg, &message); err != nil {
+			return err
+		}
+
+		return s.refreshSuggestions()
+	}, nil, log.Printf)
+}
+
+func (s *searchEngineUseCase) refreshSuggestions() error {
+	ctx, cancel := utils.DeadlineContext(30)
+	defer cancel()
+
+	err := s.repo.RefreshSuggestions(ctx)
+	if err != nil {
+		log.Printf("unable to refresh suggestions: %s", err.Error())
+	}
+	return err
+}
+
+// RefreshCoPurchasesEternally recomputes the co-purchases the recommendations
+// are based on every coPurchasesRefreshInterval.
+func (s *searchEngineUseCase) RefreshCoPurchasesEternally() {
+	for {
+		ctx, cancel := utils.DeadlineContext(60)
+		err := s.repo.RefreshCoPurchases(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("unable to refresh co-purchases: %s", err.Error())
+		}
+
+		time.Sleep(coPurchasesRefreshInterval)
+	}
+}
+
+func (s *searchEngineUseCase) Chart(request api.ChartRequest) api.Response {
+	if request.Period == "" {
+		request.Period = model.ChartWeek
+	}
+	if request.Entity == "" {
+		request.Entity = model.SearchEntityAlbum
+	}
+
+	if !model.IsValidChartPeriod(request.Period) {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid chart period",
+		}
+	}
+	if request.Entity != model.SearchEntityAlbum && request.Entity != model.SearchEntityArtist {
+		return &api.ErrorResponse{
+			Code:  http.StatusBadRequest,
+			Error: "invalid chart entity",
+		}
+	}
+
+	switch {
+	case request.Limit == 0:
+		request.Limit = defaultChartLimit
+	case request.Limit > maxChartLimit:
+		request.Limit = maxChartLimit
+	}
+
+	ctx, cancel := utils.DeadlineContext(5)
+	defer cancel()
+
+	entries, err := s.repo.GetChart(ctx, request.Period, request.Entity, strings.TrimSpace(request.Genre), request.Limit)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db unexpected error",
+		}
+	}
+
+	return &api.ChartResponse{
+		Code:    http.StatusOK,
+		Period:  request.Period,
+		Entity:  request.Entity,
+		Genre:   request.Genre,
+		Entries: entries,
+	}
+}
+
+func (s *searchEngineUseCase) ArtistChartPositions(artistID int) api.Response {
+	ctx, cancel := utils.DeadlineContext(2)
+	defer cancel()
+
+	positions, err := s.repo.GetArtistChartPositions(ctx, artistID)
+	if err != nil {
+		return &api.ErrorResponse{
+			Code:  http.StatusInternalServerError,
+			Error: "db unexpected error",
+		}
+	}
+
+	return &api.ChartPositionsResponse{
+		Code:      http.StatusOK,
+		Positions: positions,
+	}
+}
+
+// RefreshChartsEternally recomputes the charts every chartsRefreshInterval.
+func (s *searchEngineUseCase) RefreshChartsEternally() {
+	for {
+		ctx, cancel := utils.DeadlineContext(60)
+		err := s.repo.RefreshCharts(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("unable to refresh charts: %s", err.Error())
+		}
+
+		time.Sleep(chartsRefreshInterval)
+	}
+}
+
+func searchParams(request api.SearchRequest) (model.SearchParams, error) {
+	params := model.SearchParams{
+		Query:    strings.TrimSpace(request.Query),
+		Genre:    strings.TrimSpace(request.Genre),
+		ArtistID: request.ArtistID,
+		Sort:     request.Sort,
+		Limit:    request.Limit,
+	}
+
+	if request.NotPurchased {
+		if request.UserID <= 0 {
+			return model.SearchParams{}, errors.New("user is required to filter out purchased albums")
+		}
+		params.NotPurchasedBy = request.UserID
+	}
+
+	switch {
+	case params.Sort == "" && params.Query == "":
+		params.Sort = model.SearchSortName
+	case params.Sort == "":
+		params.Sort = model.SearchSortRelevance
+	case !model.IsValidSearchSort(params.Sort):
+		return model.SearchParams{}, errors.New("invalid sort order")
+	}
+
+	switch {
+	case params.Limit == 0:
+		params.Limit = defaultSearchLimit
+	case params.Limit > maxSearchLimit:
+		params.Limit = maxSearchLimit
+	}
+
+	var err error
+	params.MinPrice, err = searchPrice(request.MinPrice)
+	if err != nil {
+		return model.SearchParams{}, err
+	}
+	params.MaxPrice, err = searchPrice(request.MaxPrice)
+	if err != nil {
+		return model.SearchParams{}, err
+	}
+
+	if request.Cursor != "" {
+		cursor, err := decodeCursor(request.Cursor)
+		if err != nil || cursor.Sort != params.Sort {
+			return model.SearchParams{}, errors.New("invalid cursor")
+		}
+		params.After = &cursor
+	}
+
+	return params, nil
+}
+
+func searchPrice(value json.Number) (*model.Money, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	price, err := model.ParseMoney(value.String(), model.BaseCurrency)
+	if err != nil || price.IsNegative() {
+		return nil, errors.New("invalid price")
+	}
+	return &price, nil
+}
+
+// withoutLast drops the entity that has only been fetched to find out whether
+// there is a next page.
+func withoutLast(artists []model.Artist, albums []model.Album, extra model.SearchMatch) ([]model.Artist, []model.Album) {
+	switch {
+	case extra.Entity == model.SearchEntityArtist && len(artists) > 0 && artists[len(artists)-1].ID == extra.ID:
+		artists = artists[:len(artists)-1]
+	case extra.Entity == model.SearchEntityAlbum && len(albums) > 0 && albums[len(albums)-1].ID == extra.ID:
+		albums = albums[:len(albums)-1]
+	}
+	return artists, albums
+}
+
+func encodeCursor(cursor model.SearchCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (model.SearchCursor, error) {
+	var cursor model.SearchCursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+
+	err = json.Unmarshal(raw, &cursor)
+	return cursor, err
 }
 
 func (s *searchEngineUseCase) RandomEntities(artistsCount, albumsCount uint) api.Response {
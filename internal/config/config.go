@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +14,8 @@ type Config struct {
 	PostgresUser        string
 	PostgresPassword    string
 	KafkaPort           string
+	KafkaMaxRetries     int
+	KafkaRetryBackoff   time.Duration
 	RedisPort           string
 	JwtSecretKey        string
 	AuthorizationPort   string
@@ -35,6 +39,8 @@ func LoadConfig() (*Config, error) {
 		PostgresUser:        os.Getenv("POSTGRES_USER"),
 		PostgresPassword:    os.Getenv("POSTGRES_PASSWORD"),
 		KafkaPort:           os.Getenv("KAFKA_PORT"),
+		KafkaMaxRetries:     getIntEnv("KAFKA_MAX_RETRIES", 3),
+		KafkaRetryBackoff:   time.Duration(getIntEnv("KAFKA_RETRY_BACKOFF_MS", 200)) * time.Millisecond,
 		RedisPort:           os.Getenv("REDIS_PORT"),
 		JwtSecretKey:        os.Getenv("JWT_SECRET_KEY"),
 		AuthorizationPort:   os.Getenv("AUTHORIZATION_PORT"),
@@ -46,3 +52,11 @@ func LoadConfig() (*Config, error) {
 		GatewayPort:         os.Getenv("GATEWAY_PORT"),
 	}, nil
 }
+
+func getIntEnv(name string, defaultValue int) int {
+	result, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return result
+}
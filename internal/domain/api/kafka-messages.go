@@ -1,24 +1,48 @@
 package api
 
+import (
+	"time"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+)
+
 type MessageType uint
 
 const (
 	Buy MessageType = iota
 	Deposit
 	Delete
+	Refund
 )
 
 type MoneyOperationKafkaMessage struct {
-	Type    MessageType `json:"type"`
-	UserID  int         `json:"userID"`
-	Diff    uint        `json:"diff,omitempty"`
-	OrderID int         `json:"albumID,omitempty"`
+	Type           MessageType `json:"type"`
+	UserID         int         `json:"userID"`
+	Amount         model.Money `json:"amount"`
+	OrderID        int         `json:"albumID,omitempty"`
+	IdempotencyKey string      `json:"idempotencyKey"`
 }
 
+// NotificationKafkaMessage tells the user about an operation or an event.
+// Success is omitted for the events, e.g. a deleted album, which are neither
+// successes nor failures. Time is when it has happened, not when the
+// notification is consumed. Key tells a redelivered message from a new one.
 type NotificationKafkaMessage struct {
-	Type      MessageType `json:"type"`
-	UserID    int         `json:"userID"`
-	AlbumName string      `json:"albumName,omitempty"`
-	OrderID   int         `json:"orderID,omitempty"`
-	Success   *bool       `json:"success,omitempty"`
+	Key       string       `json:"key,omitempty"`
+	Type      MessageType  `json:"type"`
+	UserID    int          `json:"userID"`
+	AlbumID   int          `json:"albumID,omitempty"`
+	AlbumName string       `json:"albumName,omitempty"`
+	OrderID   int          `json:"orderID,omitempty"`
+	Amount    *model.Money `json:"amount,omitempty"`
+	Success   *bool        `json:"success,omitempty"`
+	Time      time.Time    `json:"time"`
+}
+
+// CatalogKafkaMessage tells that an artist, an album or a track has been
+// added, changed or deleted. Entity is empty when the whole catalog may have
+// changed, e.g. after a dump has been loaded.
+type CatalogKafkaMessage struct {
+	Entity string `json:"entity,omitempty"`
+	ID     int    `json:"id,omitempty"`
 }
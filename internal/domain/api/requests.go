@@ -1,16 +1,47 @@
 package api
 
+import "encoding/json"
+
 type OrderActionRequest struct {
 	UserID  int `json:"userID" binding:"required"`
 	AlbumID int `json:"albumID" binding:"required"`
 }
 
+// Amounts in requests are decimal numbers in major units, like 12.50. They are
+// kept as json.Number so that they never pass through a float.
 type DepositRequest struct {
-	Money uint `json:"money" binding:"required"`
+	Money    json.Number `json:"money" binding:"required"`
+	Currency string      `json:"currency,omitempty"`
 }
 
+// SearchRequest is bound from a JSON body as well as from the URL query.
+// Prices are decimal numbers in major units of the base currency.
 type SearchRequest struct {
-	Query string `json:"query" binding:"required"`
+	Query        string      `json:"query" form:"q"`
+	Genre        string      `json:"genre" form:"genre"`
+	MinPrice     json.Number `json:"minPrice" form:"minPrice"`
+	MaxPrice     json.Number `json:"maxPrice" form:"maxPrice"`
+	ArtistID     int         `json:"artistID" form:"artistID"`
+	NotPurchased bool        `json:"notPurchased" form:"notPurchased"`
+	Sort         string      `json:"sort" form:"sort"`
+	Cursor       string      `json:"cursor" form:"cursor"`
+	Limit        uint        `json:"limit" form:"limit"`
+
+	// UserID is the user whose purchases NotPurchased refers to, the gateway
+	// sets it from the token.
+	UserID int `json:"userID" form:"userID"`
+}
+
+type SuggestRequest struct {
+	Query string `form:"q" binding:"required"`
+	Limit uint   `form:"limit"`
+}
+
+type ChartRequest struct {
+	Period string `form:"period"`
+	Entity string `form:"entity"`
+	Genre  string `form:"genre"`
+	Limit  uint   `form:"limit"`
 }
 
 type RandomEntitiesRequest struct {
@@ -18,14 +49,73 @@ type RandomEntitiesRequest struct {
 	AlbumsCount  uint `json:"albumsCount" binding:"required"`
 }
 
+// RecommendationsRequest is RandomEntitiesRequest for a particular user. The
+// gateway sets UserID from the token, it is 0 for anonymous users.
+type RecommendationsRequest struct {
+	ArtistsCount uint `json:"artistsCount" binding:"required"`
+	AlbumsCount  uint `json:"albumsCount" binding:"required"`
+	UserID       int  `json:"userID"`
+}
+
 type RegistrationRequest struct {
 	Email    string `json:"email" binding:"required"`
 	IsAdmin  *bool  `json:"isAdmin" binding:"required"`
 	Nickname string `json:"nickname" binding:"required"`
 	ImageURL string `json:"imageURL" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Currency string `json:"currency,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
 }
 
+// NotificationSubscribeRequest may pick the locale of the messages, the
+// Accept-Language header of the upgrade request is used otherwise.
 type NotificationSubscribeRequest struct {
-	Jwt string `json:"jwt" binding:"required"`
+	Jwt    string `json:"jwt" binding:"required"`
+	Locale string `json:"locale"`
+}
+
+// MarkNotificationsReadRequest marks all the notifications as read when IDs
+// are omitted.
+type MarkNotificationsReadRequest struct {
+	IDs []int `json:"ids"`
+}
+
+type ArtistRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Genre    string `json:"genre" binding:"required"`
+	ImageURL string `json:"imageURL" binding:"required"`
+}
+
+type TrackRequest struct {
+	Name   string `json:"name" binding:"required"`
+	Number int    `json:"number" binding:"required"`
+}
+
+type AlbumRequest struct {
+	Name     string         `json:"name" binding:"required"`
+	ArtistID int            `json:"artistID" binding:"required"`
+	ImageURL string         `json:"imageURL" binding:"required"`
+	Price    json.Number    `json:"price" binding:"required"`
+	Tracks   []TrackRequest `json:"tracks,omitempty" binding:"omitempty,dive"`
+}
+
+type ExchangeRateRequest struct {
+	Rate json.Number `json:"rate" binding:"required"`
+}
+
+// ExchangeRatesRequest is the format of a rates file, currency code to rate,
+// e.g. {"rates": {"EUR": 0.92, "GBP": 0.79}}.
+type ExchangeRatesRequest struct {
+	Rates map[string]json.Number `json:"rates" binding:"required,min=1"`
+}
+
+type CurrencyRequest struct {
+	Currency string `json:"currency" binding:"required"`
+}
+
+type RepublishRequest struct {
+	IDs []int `json:"ids" binding:"required,min=1"`
 }
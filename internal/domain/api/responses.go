@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"github.com/allnightmarel0Ng/albums/internal/domain/model"
 )
 
@@ -18,16 +20,27 @@ func (e *ErrorResponse) GetCode() int {
 }
 
 type AuthenticationResponse struct {
-	Code    int    `json:"-"`
-	Error   string `json:"error,omitempty"`
-	Jwt     string `json:"jwt,omitempty"`
-	IsAdmin *bool  `json:"isAdmin,omitempty"`
+	Code         int    `json:"-"`
+	Error        string `json:"error,omitempty"`
+	Jwt          string `json:"jwt,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	ExpiresIn    int    `json:"expiresIn,omitempty"`
+	IsAdmin      *bool  `json:"isAdmin,omitempty"`
 }
 
 func (a *AuthenticationResponse) GetCode() int {
 	return a.Code
 }
 
+type SessionsResponse struct {
+	Code     int             `json:"-"`
+	Sessions []model.Session `json:"sessions"`
+}
+
+func (s *SessionsResponse) GetCode() int {
+	return s.Code
+}
+
 type UserProfileResponse struct {
 	Code      int           `json:"-"`
 	User      model.User    `json:"user"`
@@ -97,16 +110,59 @@ func (u *UnpaidUserOrderResponse) GetCode() int {
 	return u.Code
 }
 
+type WishlistResponse struct {
+	Code   int           `json:"-"`
+	Albums []model.Album `json:"albums"`
+}
+
+func (w *WishlistResponse) GetCode() int {
+	return w.Code
+}
+
 type SearchEngineResponse struct {
-	Code    int            `json:"-"`
-	Artists []model.Artist `json:"artists,omitempty"`
-	Albums  []model.Album  `json:"albums,omitempty"`
+	Code       int                 `json:"-"`
+	Artists    []model.Artist      `json:"artists,omitempty"`
+	Albums     []model.Album       `json:"albums,omitempty"`
+	Matches    []model.SearchMatch `json:"matches,omitempty"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+
+	Recommendations []model.Recommendation `json:"recommendations,omitempty"`
 }
 
 func (s *SearchEngineResponse) GetCode() int {
 	return s.Code
 }
 
+type SuggestionsResponse struct {
+	Code        int                `json:"-"`
+	Suggestions []model.Suggestion `json:"suggestions"`
+}
+
+func (s *SuggestionsResponse) GetCode() int {
+	return s.Code
+}
+
+type ChartResponse struct {
+	Code    int                `json:"-"`
+	Period  string             `json:"period"`
+	Entity  string             `json:"entity"`
+	Genre   string             `json:"genre,omitempty"`
+	Entries []model.ChartEntry `json:"entries"`
+}
+
+func (c *ChartResponse) GetCode() int {
+	return c.Code
+}
+
+type ChartPositionsResponse struct {
+	Code      int                   `json:"-"`
+	Positions []model.ChartPosition `json:"positions"`
+}
+
+func (c *ChartPositionsResponse) GetCode() int {
+	return c.Code
+}
+
 type BuyLogsResponse struct {
 	Code      int            `json:"-"`
 	Logs      []model.BuyLog `json:"logs"`
@@ -117,7 +173,84 @@ func (b *BuyLogsResponse) GetCode() int {
 	return b.Code
 }
 
+type CreatedResponse struct {
+	Code int `json:"-"`
+	ID   int `json:"id"`
+}
+
+func (c *CreatedResponse) GetCode() int {
+	return c.Code
+}
+
+type DeadLettersResponse struct {
+	Code        int                `json:"-"`
+	DeadLetters []model.DeadLetter `json:"deadLetters"`
+	Count       uint               `json:"count"`
+}
+
+func (d *DeadLettersResponse) GetCode() int {
+	return d.Code
+}
+
+type RepublishResponse struct {
+	Code        int   `json:"-"`
+	Republished []int `json:"republished"`
+}
+
+func (r *RepublishResponse) GetCode() int {
+	return r.Code
+}
+
+type ExchangeRatesResponse struct {
+	Code  int                  `json:"-"`
+	Rates []model.ExchangeRate `json:"rates"`
+}
+
+func (e *ExchangeRatesResponse) GetCode() int {
+	return e.Code
+}
+
+type TransactionsResponse struct {
+	Code         int                 `json:"-"`
+	Transactions []model.LedgerEntry `json:"transactions"`
+	Count        uint                `json:"count"`
+}
+
+func (t *TransactionsResponse) GetCode() int {
+	return t.Code
+}
+
+type OperationResponse struct {
+	Code      int             `json:"-"`
+	Operation model.Operation `json:"operation"`
+}
+
+func (o *OperationResponse) GetCode() int {
+	return o.Code
+}
+
+// NotificationResponse carries what the notification is about, so that the
+// clients can act on it, and a message in the requested locale to show.
+// CreatedAt is when the event has happened.
 type NotificationResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	ID        int          `json:"id"`
+	Type      string       `json:"type"`
+	Status    string       `json:"status"`
+	OrderID   int          `json:"orderID,omitempty"`
+	AlbumID   int          `json:"albumID,omitempty"`
+	AlbumName string       `json:"albumName,omitempty"`
+	Amount    *model.Money `json:"amount,omitempty"`
+	Message   string       `json:"message"`
+	IsRead    bool         `json:"isRead"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type NotificationsResponse struct {
+	Code          int                    `json:"-"`
+	Notifications []NotificationResponse `json:"notifications"`
+	Count         uint                   `json:"count"`
+}
+
+func (n *NotificationsResponse) GetCode() int {
+	return n.Code
 }
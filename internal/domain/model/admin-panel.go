@@ -8,3 +8,14 @@ type BuyLog struct {
 	Album       Album     `json:"album"`
 	LoggingTime time.Time `json:"loggingTime"`
 }
+
+type DeadLetter struct {
+	ID            int        `json:"id"`
+	Topic         string     `json:"topic"`
+	Key           string     `json:"key"`
+	Payload       string     `json:"payload"`
+	Error         string     `json:"error"`
+	Attempts      int        `json:"attempts"`
+	FailedAt      time.Time  `json:"failedAt"`
+	RepublishedAt *time.Time `json:"republishedAt,omitempty"`
+}
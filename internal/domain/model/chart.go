@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+const (
+	ChartDay   = "day"
+	ChartWeek  = "week"
+	ChartMonth = "month"
+)
+
+func IsValidChartPeriod(period string) bool {
+	return period == ChartDay || period == ChartWeek || period == ChartMonth
+}
+
+// ChartEntry is a place in a chart, it holds either an artist or an album.
+// Entries with the same number of purchases share the position.
+type ChartEntry struct {
+	Position  int     `json:"position"`
+	Purchases int     `json:"purchases"`
+	Artist    *Artist `json:"artist,omitempty"`
+	Album     *Album  `json:"album,omitempty"`
+
+	Entity string `json:"-"`
+	ID     int    `json:"-"`
+}
+
+// ChartPosition is the place of an artist in the charts of a period.
+type ChartPosition struct {
+	Period        string    `json:"period"`
+	Position      int       `json:"position"`
+	Genre         string    `json:"genre"`
+	GenrePosition int       `json:"genrePosition"`
+	Purchases     int       `json:"purchases"`
+	ComputedAt    time.Time `json:"computedAt"`
+}
@@ -1,12 +1,205 @@
 package model
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaseCurrency is the currency albums are priced in and exchange rates are
+// quoted against.
+const BaseCurrency = "USD"
+
+// minorUnitsInMajor is the number of minor units in one major unit. Every
+// currency the store works with has two decimal places.
+const minorUnitsInMajor = 100
+
+var (
+	ErrInvalidAmount    = errors.New("invalid money amount")
+	ErrInvalidCurrency  = errors.New("invalid currency code")
+	ErrCurrencyMismatch = errors.New("money amounts have different currencies")
+	ErrInvalidRate      = errors.New("exchange rate must be a positive decimal number")
+)
+
+// Money is an amount in minor units of its currency (cents for USD), so sums
+// and comparisons are exact. It is stored in Postgres as a BIGINT amount next
+// to a CHAR(3) currency column.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{
+		Amount:   amount,
+		Currency: currency,
+	}
+}
+
+// ParseMoney parses a decimal amount in major units, like "12" or "12.50".
+// An empty currency means BaseCurrency.
+func ParseMoney(value string, currency string) (Money, error) {
+	if currency == "" {
+		currency = BaseCurrency
+	}
+	if !IsValidCurrency(currency) {
+		return Money{}, ErrInvalidCurrency
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	if whole == "" || (hasFraction && (fraction == "" || len(fraction) > 2)) {
+		return Money{}, ErrInvalidAmount
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	major, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+
+	minor, err := strconv.ParseUint(fraction, 10, 8)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+
+	if major > (1<<63-1-minor)/minorUnitsInMajor {
+		return Money{}, ErrInvalidAmount
+	}
+
+	amount := int64(major*minorUnitsInMajor + minor)
+	if negative {
+		amount = -amount
+	}
+
+	return NewMoney(amount, currency), nil
+}
+
+func IsValidCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return NewMoney(m.Amount+other.Amount, m.Currency), nil
+}
+
+func (m Money) Less(other Money) (bool, error) {
+	if m.Currency != other.Currency {
+		return false, ErrCurrencyMismatch
+	}
+	return m.Amount < other.Amount, nil
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) String() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%d.%02d %s", sign, amount/minorUnitsInMajor, amount%minorUnitsInMajor, m.Currency)
+}
 
 type Order struct {
 	ID         int       `json:"id"`
 	Orderer    User      `json:"orderer"`
 	Date       time.Time `json:"date"`
-	TotalPrice float64   `json:"totalPrice"`
+	TotalPrice Money     `json:"totalPrice"`
 	IsPaid     bool      `json:"isPaid"`
+	IsRefunded bool      `json:"isRefunded"`
 	Albums     []Album   `json:"albums,omitempty"`
+
+	// PaidAmount and ExchangeRate record what was actually charged from the
+	// balance and at which rate, once the order is paid.
+	PaidAmount   *Money `json:"paidAmount,omitempty"`
+	ExchangeRate string `json:"exchangeRate,omitempty"`
+}
+
+const (
+	LedgerDeposit    = "deposit"
+	LedgerPurchase   = "purchase"
+	LedgerRefund     = "refund"
+	LedgerAdjustment = "adjustment"
+)
+
+type LedgerEntry struct {
+	ID           int       `json:"id"`
+	Type         string    `json:"type"`
+	Amount       Money     `json:"amount"`
+	BalanceAfter Money     `json:"balanceAfter"`
+	OrderID      *int      `json:"orderID,omitempty"`
+	OperationKey string    `json:"operationKey,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ExchangeRate is how many units of Currency one unit of BaseCurrency buys.
+// Rate is kept as decimal text, as Postgres returns it, to stay exact.
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func ParseRate(rate string) (*big.Rat, error) {
+	result, ok := new(big.Rat).SetString(rate)
+	if !ok || result.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+	return result, nil
+}
+
+// ConvertMoney converts money from the currency of the from rate to the
+// currency of the to rate, rounding half away from zero like convert_amount
+// does in Postgres.
+func ConvertMoney(m Money, from, to ExchangeRate) (Money, error) {
+	if m.Currency != from.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	fromRate, err := ParseRate(from.Rate)
+	if err != nil {
+		return Money{}, err
+	}
+
+	toRate, err := ParseRate(to.Rate)
+	if err != nil {
+		return Money{}, err
+	}
+
+	converted := new(big.Rat).SetInt64(m.Amount)
+	converted.Mul(converted, toRate)
+	converted.Quo(converted, fromRate)
+
+	quotient, remainder := new(big.Int).QuoRem(converted.Num(), converted.Denom(), new(big.Int))
+	if new(big.Int).Mul(remainder.Abs(remainder), big.NewInt(2)).Cmp(converted.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(converted.Sign())))
+	}
+
+	if !quotient.IsInt64() {
+		return Money{}, ErrInvalidAmount
+	}
+
+	return NewMoney(quotient.Int64(), to.Currency), nil
 }
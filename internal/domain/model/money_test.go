@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		currency string
+		want     Money
+		err      error
+	}{
+		{name: "whole", value: "12", want: NewMoney(1200, "USD")},
+		{name: "one fraction digit", value: "12.5", want: NewMoney(1250, "USD")},
+		{name: "two fraction digits", value: "12.05", currency: "EUR", want: NewMoney(1205, "EUR")},
+		{name: "negative", value: "-12.50", want: NewMoney(-1250, "USD")},
+		{name: "negative below one", value: "-0.01", want: NewMoney(-1, "USD")},
+		{name: "zero", value: "0.00", want: NewMoney(0, "USD")},
+		{name: "largest", value: "92233720368547758.07", want: NewMoney(1<<63-1, "USD")},
+
+		{name: "fraction too long", value: "1.234", err: ErrInvalidAmount},
+		{name: "empty", value: "", err: ErrInvalidAmount},
+		{name: "sign only", value: "-", err: ErrInvalidAmount},
+		{name: "empty whole", value: ".50", err: ErrInvalidAmount},
+		{name: "empty fraction", value: "12.", err: ErrInvalidAmount},
+		{name: "double sign", value: "--1", err: ErrInvalidAmount},
+		{name: "plus sign", value: "+1", err: ErrInvalidAmount},
+		{name: "signed fraction", value: "1.-5", err: ErrInvalidAmount},
+		{name: "not a number", value: "1e3", err: ErrInvalidAmount},
+		{name: "overflow by minor units", value: "92233720368547758.08", err: ErrInvalidAmount},
+		{name: "overflow by major units", value: "92233720368547759", err: ErrInvalidAmount},
+		{name: "overflow of uint63", value: "9223372036854775808", err: ErrInvalidAmount},
+		{name: "invalid currency", value: "1", currency: "usd", err: ErrInvalidCurrency},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseMoney(test.value, test.currency)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ParseMoney(%q, %q) error = %v, want %v", test.value, test.currency, err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("ParseMoney(%q, %q) = %+v, want %+v", test.value, test.currency, got, test.want)
+			}
+		})
+	}
+}
+
+func TestConvertMoney(t *testing.T) {
+	usd := ExchangeRate{Currency: "USD", Rate: "1"}
+	eur := ExchangeRate{Currency: "EUR", Rate: "1.2"}
+	half := ExchangeRate{Currency: "HLF", Rate: "0.5"}
+	low := ExchangeRate{Currency: "LOW", Rate: "0.3"}
+	double := ExchangeRate{Currency: "DBL", Rate: "2"}
+
+	tests := []struct {
+		name     string
+		money    Money
+		from, to ExchangeRate
+		want     Money
+		err      error
+	}{
+		{name: "exact", money: NewMoney(1000, "USD"), from: usd, to: eur, want: NewMoney(1200, "EUR")},
+		{name: "same currency", money: NewMoney(1000, "USD"), from: usd, to: usd, want: NewMoney(1000, "USD")},
+		{name: "half rounds up", money: NewMoney(5, "USD"), from: usd, to: half, want: NewMoney(3, "HLF")},
+		{name: "negative half rounds down", money: NewMoney(-5, "USD"), from: usd, to: half, want: NewMoney(-3, "HLF")},
+		{name: "negative one cent half", money: NewMoney(-1, "USD"), from: usd, to: half, want: NewMoney(-1, "HLF")},
+		{name: "below half", money: NewMoney(7, "USD"), from: usd, to: low, want: NewMoney(2, "LOW")},
+		{name: "negative below half", money: NewMoney(-7, "USD"), from: usd, to: low, want: NewMoney(-2, "LOW")},
+		// convert_amount, which deposits and pay_for_order use, rounds
+		// 3 * 1 / 1.2 = 2.5000000000000000 to 3, where the rounded rate
+		// 0.83333333333333333333 would give 2.4999... and 2
+		{name: "rounds like convert_amount", money: NewMoney(3, "EUR"), from: eur, to: usd, want: NewMoney(3, "USD")},
+		{name: "negative rounds like convert_amount", money: NewMoney(-3, "EUR"), from: eur, to: usd, want: NewMoney(-3, "USD")},
+
+		{name: "currency mismatch", money: NewMoney(1, "EUR"), from: usd, to: eur, err: ErrCurrencyMismatch},
+		{name: "zero rate", money: NewMoney(1, "USD"), from: usd, to: ExchangeRate{Currency: "EUR", Rate: "0"}, err: ErrInvalidRate},
+		{name: "negative rate", money: NewMoney(1, "USD"), from: ExchangeRate{Currency: "USD", Rate: "-1"}, to: eur, err: ErrInvalidRate},
+		{name: "not a rate", money: NewMoney(1, "USD"), from: usd, to: ExchangeRate{Currency: "EUR", Rate: "abc"}, err: ErrInvalidRate},
+		{name: "overflow", money: NewMoney(1<<63-1, "USD"), from: usd, to: double, err: ErrInvalidAmount},
+		{name: "negative overflow", money: NewMoney(-1<<63, "USD"), from: usd, to: double, err: ErrInvalidAmount},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ConvertMoney(test.money, test.from, test.to)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ConvertMoney(%+v) error = %v, want %v", test.money, err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("ConvertMoney(%+v) = %+v, want %+v", test.money, got, test.want)
+			}
+		})
+	}
+}
@@ -12,7 +12,7 @@ type Album struct {
 	Name     string  `json:"name"`
 	Author   *Artist `json:"author,omitempty"`
 	ImageURL string  `json:"imageURL"`
-	Price    float64 `json:"price"`
+	Price    Money   `json:"price"`
 	Tracks   []Track `json:"tracks"`
 }
 
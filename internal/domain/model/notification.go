@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+const (
+	NotificationDeposit      = "deposit"
+	NotificationBuy          = "buy"
+	NotificationRefund       = "refund"
+	NotificationAlbumDeleted = "album_deleted"
+)
+
+const (
+	NotificationSucceeded = "succeeded"
+	NotificationFailed    = "failed"
+	// NotificationInfo is the status of the notifications which tell about
+	// something that has happened rather than an outcome of an operation
+	NotificationInfo = "info"
+)
+
+type Notification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Key       string    `json:"-"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"`
+	OrderID   int       `json:"orderID,omitempty"`
+	AlbumID   int       `json:"albumID,omitempty"`
+	AlbumName string    `json:"albumName,omitempty"`
+	Amount    *Money    `json:"amount,omitempty"`
+	IsRead    bool      `json:"isRead"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+const (
+	OperationDeposit = "deposit"
+	OperationBuy     = "buy"
+	OperationRefund  = "refund"
+)
+
+const (
+	OperationPending   = "pending"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+	// OperationQueued is an operation that kept failing and has been moved to
+	// the dead letters, it is finished once an admin republishes it
+	OperationQueued = "queued"
+)
+
+type Operation struct {
+	ID        string    `json:"id"`
+	UserID    int       `json:"-"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	OrderID   int       `json:"orderID,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (o Operation) IsPending() bool {
+	return o.Status == OperationPending
+}
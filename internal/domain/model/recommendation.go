@@ -0,0 +1,17 @@
+package model
+
+const (
+	RecommendationCoPurchase = "co-purchase"
+	RecommendationArtist     = "artist"
+	RecommendationGenre      = "genre"
+	RecommendationPopular    = "popular"
+)
+
+// Recommendation is an artist or an album suggested to a user, Reason is the
+// signal that contributed the most to its score.
+type Recommendation struct {
+	Entity string  `json:"entity"`
+	ID     int     `json:"id"`
+	Reason string  `json:"reason"`
+	Score  float64 `json:"score"`
+}
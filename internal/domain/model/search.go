@@ -0,0 +1,75 @@
+package model
+
+const (
+	SearchEntityArtist = "artist"
+	SearchEntityAlbum  = "album"
+	SearchEntityTrack  = "track"
+)
+
+const (
+	SearchFieldName  = "name"
+	SearchFieldGenre = "genre"
+	SearchFieldTrack = "track"
+)
+
+const (
+	SearchSortRelevance = "relevance"
+	SearchSortName      = "name"
+	SearchSortPrice     = "price"
+	SearchSortPriceDesc = "price_desc"
+	SearchSortNewest    = "newest"
+)
+
+// SearchMatch describes why an artist or an album is in the search results:
+// the field that matched the query and the text of that field. An album found
+// by one of its tracks has SearchFieldTrack as field and the track name as
+// value. Field and value are empty when the search has no query.
+type SearchMatch struct {
+	Entity  string  `json:"entity"`
+	ID      int     `json:"id"`
+	Field   string  `json:"field,omitempty"`
+	Value   string  `json:"value,omitempty"`
+	Rank    float64 `json:"rank"`
+	SortKey string  `json:"-"`
+}
+
+// SearchCursor points at the last result of a page, the next page starts
+// right after it. A cursor is only valid for the sort order it was made for.
+type SearchCursor struct {
+	Sort    string `json:"s"`
+	SortKey string `json:"k"`
+	Entity  string `json:"e"`
+	ID      int    `json:"i"`
+}
+
+// SearchParams are the search query and filters. Price filters, like album
+// prices, are in the base currency. Artists have no price and purchases, so
+// they are left out once a price filter or NotPurchasedBy is set.
+type SearchParams struct {
+	Query          string
+	Genre          string
+	MinPrice       *Money
+	MaxPrice       *Money
+	ArtistID       int
+	NotPurchasedBy int
+	Sort           string
+	After          *SearchCursor
+	Limit          uint
+}
+
+func IsValidSearchSort(sort string) bool {
+	switch sort {
+	case SearchSortRelevance, SearchSortName, SearchSortPrice, SearchSortPriceDesc, SearchSortNewest:
+		return true
+	default:
+		return false
+	}
+}
+
+// Suggestion is a name completion. AlbumID is the album of a track.
+type Suggestion struct {
+	Kind    string `json:"kind"`
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	AlbumID int    `json:"albumID,omitempty"`
+}
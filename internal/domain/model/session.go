@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type Session struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UserAgent string    `json:"userAgent"`
+	Current   bool      `json:"current,omitempty"`
+}
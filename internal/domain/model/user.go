@@ -1,10 +1,11 @@
 package model
 
 type User struct {
-	ID       int     `json:"id"`
-	Email    string  `json:"email"`
-	IsAdmin  bool    `json:"isAdmin"`
-	Nickname string  `json:"nickname"`
-	Balance  float64 `json:"balance"`
-	ImageURL string  `json:"imageURL"`
+	ID       int    `json:"id"`
+	Email    string `json:"email"`
+	IsAdmin  bool   `json:"isAdmin"`
+	Nickname string `json:"nickname"`
+	Balance  Money  `json:"balance"`
+	Currency string `json:"currency"`
+	ImageURL string `json:"imageURL"`
 }
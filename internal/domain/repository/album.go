@@ -9,7 +9,7 @@ import (
 )
 
 const (
-	selectUsersPurchasedAlbumsSQL =
+	selectAlbumsByIDsSQL =
 	/* sql */ `SELECT
 					a.id,
 					a.name,
@@ -19,70 +19,54 @@ const (
 					ar.image_url,
 					a.image_url,
 					a.price,
+					a.currency,
 					t.id,
 					t.name,
 					t.number
+				FROM public.albums AS a
+				JOIN public.artists AS ar ON a.artist_id = ar.id
+				LEFT JOIN public.tracks AS t ON t.album_id = a.id
+				WHERE a.id = ANY($1)
+				ORDER BY a.id, t.number;`
+
+	selectUsersPurchasedAlbumIDsSQL =
+	/* sql */ `SELECT a.id
 				FROM public.purchased_albums AS pu
 				JOIN public.albums AS a ON pu.album_id = a.id
-				JOIN public.artists AS ar ON a.artist_id = ar.id
-				RIGHT JOIN public.tracks AS t ON t.album_id = a.id
 				WHERE pu.user_id = $1
-				ORDER BY a.name, t.number;`
-
-	selectAlbumsLikeNameSQL =
-	/* sql */ `SELECT 
-					a.id,
-					a.name,
-					ar.id,
-					ar.name,
-					ar.genre,
-					ar.image_url,
-					a.image_url,
-					a.price,
-					t.id,
-					t.name,
-					t.number
-				FROM public.tracks AS t
-				LEFT JOIN public.albums AS a ON t.album_id = a.id
-				JOIN public.artists AS ar ON a.artist_id = ar.id
-				WHERE a.name ILIKE $1
-				ORDER BY t.number;`
+				ORDER BY a.name;`
 
-	selectArtistsAlbumsSQL =
-	/* sql */ `SELECT
-					a.id,
-					a.name,
-					ar.id,
-					ar.name,
-					ar.genre,
-					ar.image_url,
-					a.image_url,
-					a.price,
-					t.id,
-					t.name,
-					t.number
-				FROM public.tracks AS t
-				LEFT JOIN public.albums AS a ON t.album_id = a.id
-				JOIN public.artists AS ar ON a.artist_id = ar.id
-				WHERE ar.id = $1
-				ORDER BY t.number;`
+	selectArtistsAlbumIDsSQL =
+	/* sql */ `SELECT id
+				FROM public.albums
+				WHERE artist_id = $1
+				ORDER BY id;`
+
+	// every probe picks the album with the nearest random_key above a random
+	// point, which is a single index lookup; there are twice as many probes as
+	// albums asked for since some of them land on the same album
+	selectRandomNAlbumIDsSQL =
+	/* sql */ `SELECT sample.id
+				FROM (
+					SELECT n, RANDOM() AS point
+					FROM generate_series(1, $1 * 2) AS n
+				) AS probe
+				CROSS JOIN LATERAL (
+					SELECT id
+					FROM public.albums
+					WHERE random_key >= probe.point
+					ORDER BY random_key
+					LIMIT 1
+				) AS sample
+				GROUP BY sample.id
+				ORDER BY MIN(probe.n)
+				LIMIT $1;`
 
-	selectRandomNAlbumsSQL =
-	/* sql */ `SELECT 
-					a.id,
-					a.name,
-					ar.id,
-					ar.name,
-					ar.genre,
-					ar.image_url,
-					a.image_url,
-					a.price,
-					t.id,
-					t.name,
-					t.number
-				FROM public.tracks AS t
-				LEFT JOIN public.albums AS a ON t.album_id = a.id
-				JOIN public.artists AS ar ON a.artist_id = ar.id
+	// fallback for the case when probes found less albums than asked, which
+	// happens when the table is not much larger than the sample
+	selectShuffledAlbumIDsSQL =
+	/* sql */ `SELECT id
+				FROM public.albums
 				ORDER BY RANDOM()
 				LIMIT $1;`
 
@@ -90,39 +74,51 @@ const (
 	/* sql */ `DELETE FROM public.albums
 				WHERE id = $1;`
 
-	selectAlbumByIdSQL =
-	/* sql */ `SELECT
-					a.id,
-					a.name,
-					ar.id,
-					ar.name,
-					ar.genre,
-					ar.image_url,
-					a.image_url,
-					a.price,
-					t.id,
-					t.name,
-					t.number
-				FROM public.tracks AS t
-				LEFT JOIN public.albums AS a ON t.album_id = a.id
-				JOIN public.artists AS ar ON a.artist_id = ar.id
-				WHERE a.id = $1
-				ORDER BY t.number;`
-
 	selectAlbumNameSQL =
 	/* sql */ `SELECT name
 				FROM public.albums
 				WHERE id = $1;`
+
+	insertAlbumSQL =
+	/* sql */ `INSERT INTO public.albums (name, artist_id, image_url, price, currency)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id;`
+
+	updateAlbumSQL =
+	/* sql */ `UPDATE public.albums
+				SET name = $2,
+					artist_id = $3,
+					image_url = $4,
+					price = $5,
+					currency = $6
+				WHERE id = $1
+				RETURNING id;`
+
+	insertTrackSQL =
+	/* sql */ `INSERT INTO public.tracks (album_id, name, number)
+				VALUES ($1, $2, $3)
+				RETURNING id;`
+
+	updateTrackSQL =
+	/* sql */ `UPDATE public.tracks
+				SET name = $2,
+					number = $3
+				WHERE id = $1
+				RETURNING id;`
 )
 
 type AlbumRepository interface {
 	GetUsersPurchasedAlbums(ctx context.Context, userID int) ([]model.Album, error)
-	GetAlbumsLikeName(ctx context.Context, name string) ([]model.Album, error)
+	GetAlbumsByIDs(ctx context.Context, ids []int) ([]model.Album, error)
 	GetArtistsAlbums(ctx context.Context, artistID int) ([]model.Album, error)
 	GetRandomNAlbums(ctx context.Context, count uint) ([]model.Album, error)
 	DeleteAlbum(ctx context.Context, albumID int) error
 	GetAlbumByID(ctx context.Context, albumID int) (model.Album, error)
 	GetAlbumName(ctx context.Context, albumID int) (string, error)
+	AddAlbum(ctx context.Context, album model.Album) (int, error)
+	UpdateAlbum(ctx context.Context, album model.Album) error
+	AddTrack(ctx context.Context, albumID int, track model.Track) (int, error)
+	UpdateTrack(ctx context.Context, track model.Track) error
 }
 
 type albumRepository struct {
@@ -135,33 +131,39 @@ func NewAlbumRepository(db postgres.Database) AlbumRepository {
 	}
 }
 
+// albumsFromRows groups album rows joined with their tracks, albums without
+// tracks come as a single row with NULL track columns
 func albumsFromRows(rows postgres.Rows) ([]model.Album, error) {
 	albumsMap := make(map[int]*model.Album)
 	var ids []int
 
 	for rows.Next() {
 		var (
-			album  model.Album
-			track  model.Track
-			author model.Artist
+			album       model.Album
+			author      model.Artist
+			trackID     *int
+			trackName   *string
+			trackNumber *int
 		)
 
 		err := rows.Scan(&album.ID, &album.Name, &author.ID,
-			&author.Name, &author.Genre, &author.ImageURL, &album.ImageURL, &album.Price,
-			&track.ID, &track.Name, &track.Number)
+			&author.Name, &author.Genre, &author.ImageURL, &album.ImageURL, &album.Price.Amount, &album.Price.Currency,
+			&trackID, &trackName, &trackNumber)
 		if err != nil {
 			return nil, err
 		}
 
-		album.Author = &author
-
-		_, ok := albumsMap[album.ID]
+		current, ok := albumsMap[album.ID]
 		if !ok {
-			album.Tracks = []model.Track{track}
-			albumsMap[album.ID] = &album
+			album.Author = &author
+			album.Tracks = []model.Track{}
+			current = &album
+			albumsMap[album.ID] = current
 			ids = append(ids, album.ID)
-		} else {
-			albumsMap[album.ID].Tracks = append(albumsMap[album.ID].Tracks, track)
+		}
+
+		if trackID != nil {
+			current.Tracks = append(current.Tracks, model.Track{ID: *trackID, Name: *trackName, Number: *trackNumber})
 		}
 	}
 
@@ -173,42 +175,97 @@ func albumsFromRows(rows postgres.Rows) ([]model.Album, error) {
 	return result, nil
 }
 
-func (a *albumRepository) GetUsersPurchasedAlbums(ctx context.Context, userID int) ([]model.Album, error) {
-	rows, err := a.db.Query(ctx, selectUsersPurchasedAlbumsSQL, userID)
+func idsFromRows(rows postgres.Rows) ([]int, error) {
+	var result []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+func (a *albumRepository) queryAlbumIDs(ctx context.Context, sql string, args ...interface{}) ([]int, error) {
+	rows, err := a.db.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return albumsFromRows(rows)
+	return idsFromRows(rows)
+}
+
+func (a *albumRepository) GetUsersPurchasedAlbums(ctx context.Context, userID int) ([]model.Album, error) {
+	ids, err := a.queryAlbumIDs(ctx, selectUsersPurchasedAlbumIDsSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.GetAlbumsByIDs(ctx, ids)
 }
 
-func (a *albumRepository) GetAlbumsLikeName(ctx context.Context, name string) ([]model.Album, error) {
-	rows, err := a.db.Query(ctx, selectAlbumsLikeNameSQL, name)
+// GetAlbumsByIDs loads whole albums in the order of the given IDs, the IDs
+// of missing albums are skipped
+func (a *albumRepository) GetAlbumsByIDs(ctx context.Context, ids []int) ([]model.Album, error) {
+	if len(ids) == 0 {
+		return []model.Album{}, nil
+	}
+
+	rows, err := a.db.Query(ctx, selectAlbumsByIDsSQL, ids)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return albumsFromRows(rows)
+	albums, err := albumsFromRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]model.Album, len(albums))
+	for _, album := range albums {
+		byID[album.ID] = album
+	}
+
+	result := make([]model.Album, 0, len(albums))
+	for _, id := range ids {
+		album, ok := byID[id]
+		if !ok {
+			continue
+		}
+		result = append(result, album)
+		delete(byID, id)
+	}
+
+	return result, nil
 }
 
 func (a *albumRepository) GetArtistsAlbums(ctx context.Context, artistID int) ([]model.Album, error) {
-	rows, err := a.db.Query(ctx, selectArtistsAlbumsSQL, artistID)
+	ids, err := a.queryAlbumIDs(ctx, selectArtistsAlbumIDsSQL, artistID)
 	if err != nil {
 		return nil, err
 	}
 
-	return albumsFromRows(rows)
+	return a.GetAlbumsByIDs(ctx, ids)
 }
 
 func (a *albumRepository) GetRandomNAlbums(ctx context.Context, count uint) ([]model.Album, error) {
-	rows, err := a.db.Query(ctx, selectRandomNAlbumsSQL, count)
+	ids, err := a.queryAlbumIDs(ctx, selectRandomNAlbumIDsSQL, count)
 	if err != nil {
 		return nil, err
 	}
 
-	return albumsFromRows(rows)
+	if uint(len(ids)) < count {
+		ids, err = a.queryAlbumIDs(ctx, selectShuffledAlbumIDsSQL, count)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a.GetAlbumsByIDs(ctx, ids)
 }
 
 func (a *albumRepository) DeleteAlbum(ctx context.Context, albumID int) error {
@@ -216,19 +273,13 @@ func (a *albumRepository) DeleteAlbum(ctx context.Context, albumID int) error {
 }
 
 func (a *albumRepository) GetAlbumByID(ctx context.Context, albumID int) (model.Album, error) {
-	rows, err := a.db.Query(ctx, selectAlbumByIdSQL, albumID)
-	if err != nil {
-		return model.Album{}, err
-	}
-	defer rows.Close()
-
-	result, err := albumsFromRows(rows)
+	result, err := a.GetAlbumsByIDs(ctx, []int{albumID})
 	if err != nil {
 		return model.Album{}, err
 	}
 
 	if len(result) != 1 {
-		return model.Album{}, fmt.Errorf("album not found")
+		return model.Album{}, ErrAlbumNotFound
 	}
 
 	return result[0], nil
@@ -239,3 +290,89 @@ func (a *albumRepository) GetAlbumName(ctx context.Context, id int) (string, err
 	err := a.db.QueryRow(ctx, selectAlbumNameSQL, id).Scan(&result)
 	return result, err
 }
+
+func (a *albumRepository) AddAlbum(ctx context.Context, album model.Album) (id int, err error) {
+	if album.Author == nil {
+		return 0, ErrArtistNotFound
+	}
+
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered from panic: %v", r)
+		}
+
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	err = tx.QueryRow(ctx, insertAlbumSQL, album.Name, album.Author.ID, album.ImageURL, album.Price.Amount, album.Price.Currency).Scan(&id)
+	if err != nil {
+		if postgres.ErrorCode(err) == postgres.ForeignKeyViolation {
+			return 0, ErrArtistNotFound
+		}
+		return 0, err
+	}
+
+	for _, track := range album.Tracks {
+		var trackID int
+		err = tx.QueryRow(ctx, insertTrackSQL, id, track.Name, track.Number).Scan(&trackID)
+		if err != nil {
+			return 0, trackError(err)
+		}
+	}
+
+	return id, nil
+}
+
+func (a *albumRepository) UpdateAlbum(ctx context.Context, album model.Album) error {
+	if album.Author == nil {
+		return ErrArtistNotFound
+	}
+
+	var id int
+	err := a.db.QueryRow(ctx, updateAlbumSQL, album.ID, album.Name, album.Author.ID, album.ImageURL, album.Price.Amount, album.Price.Currency).Scan(&id)
+	switch {
+	case postgres.IsNoRows(err):
+		return ErrAlbumNotFound
+	case postgres.ErrorCode(err) == postgres.ForeignKeyViolation:
+		return ErrArtistNotFound
+	}
+	return err
+}
+
+func (a *albumRepository) AddTrack(ctx context.Context, albumID int, track model.Track) (int, error) {
+	var id int
+	err := a.db.QueryRow(ctx, insertTrackSQL, albumID, track.Name, track.Number).Scan(&id)
+	if err != nil {
+		return 0, trackError(err)
+	}
+	return id, nil
+}
+
+func (a *albumRepository) UpdateTrack(ctx context.Context, track model.Track) error {
+	var id int
+	err := a.db.QueryRow(ctx, updateTrackSQL, track.ID, track.Name, track.Number).Scan(&id)
+	if postgres.IsNoRows(err) {
+		return ErrTrackNotFound
+	}
+	return trackError(err)
+}
+
+func trackError(err error) error {
+	switch postgres.ErrorCode(err) {
+	case postgres.UniqueViolation:
+		return ErrTrackNumberTaken
+	case postgres.ForeignKeyViolation:
+		return ErrAlbumNotFound
+	default:
+		return err
+	}
+}
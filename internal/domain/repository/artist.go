@@ -17,30 +17,59 @@ const (
 				FROM public.artists
 				WHERE id = $1;`
 
-	selectArtistsLikeName =
+	selectArtistsByIDsSQL =
 	/* sql */ `SELECT
 					id,
 					name,
 					genre,
 					image_url
 				FROM public.artists
-				WHERE name ILIKE $1;`
+				WHERE id = ANY($1);`
+
+	// same sampling as selectRandomNAlbumIDsSQL
+	selectRandomNArtistIDsSQL =
+	/* sql */ `SELECT sample.id
+				FROM (
+					SELECT n, RANDOM() AS point
+					FROM generate_series(1, $1 * 2) AS n
+				) AS probe
+				CROSS JOIN LATERAL (
+					SELECT id
+					FROM public.artists
+					WHERE random_key >= probe.point
+					ORDER BY random_key
+					LIMIT 1
+				) AS sample
+				GROUP BY sample.id
+				ORDER BY MIN(probe.n)
+				LIMIT $1;`
 
-	selectRandomNArtistsSQL =
-	/* sql */ `SELECT
-					id,
-					name,
-					genre,
-					image_url
+	selectShuffledArtistIDsSQL =
+	/* sql */ `SELECT id
 				FROM public.artists
 				ORDER BY RANDOM()
 				LIMIT $1;`
+
+	insertArtistSQL =
+	/* sql */ `INSERT INTO public.artists (name, genre, image_url)
+				VALUES ($1, $2, $3)
+				RETURNING id;`
+
+	updateArtistSQL =
+	/* sql */ `UPDATE public.artists
+				SET name = $2,
+					genre = $3,
+					image_url = $4
+				WHERE id = $1
+				RETURNING id;`
 )
 
 type ArtistRepository interface {
 	GetArtistByID(ctx context.Context, id int) (model.Artist, error)
-	GetArtistsLikeName(ctx context.Context, name string) ([]model.Artist, error)
+	GetArtistsByIDs(ctx context.Context, ids []int) ([]model.Artist, error)
 	GetRandomNArtists(ctx context.Context, count uint) ([]model.Artist, error)
+	AddArtist(ctx context.Context, artist model.Artist) (int, error)
+	UpdateArtist(ctx context.Context, artist model.Artist) error
 }
 
 type artistRepository struct {
@@ -59,12 +88,17 @@ func (a *artistRepository) GetArtistByID(ctx context.Context, id int) (model.Art
 	return result, err
 }
 
-func (a *artistRepository) GetArtistsLikeName(ctx context.Context, name string) ([]model.Artist, error) {
-	var result []model.Artist
-	rows, err := a.db.Query(ctx, selectArtistsLikeName, name)
+func (a *artistRepository) GetArtistsByIDs(ctx context.Context, ids []int) ([]model.Artist, error) {
+	result := []model.Artist{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	rows, err := a.db.Query(ctx, selectArtistsByIDsSQL, ids)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var current model.Artist
@@ -79,22 +113,42 @@ func (a *artistRepository) GetArtistsLikeName(ctx context.Context, name string)
 }
 
 func (a *artistRepository) GetRandomNArtists(ctx context.Context, count uint) ([]model.Artist, error) {
-	rows, err := a.db.Query(ctx, selectRandomNArtistsSQL, count)
+	ids, err := a.queryArtistIDs(ctx, selectRandomNArtistIDsSQL, count)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	result := make([]model.Artist, count)
-	index := 0
-	for rows.Next() {
-		err := rows.Scan(&result[index].ID, &result[index].Name, &result[index].Genre, &result[index].ImageURL)
+	if uint(len(ids)) < count {
+		ids, err = a.queryArtistIDs(ctx, selectShuffledArtistIDsSQL, count)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	return a.GetArtistsByIDs(ctx, ids)
+}
 
-		index++
+func (a *artistRepository) queryArtistIDs(ctx context.Context, sql string, args ...interface{}) ([]int, error) {
+	rows, err := a.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return result, nil
+	return idsFromRows(rows)
+}
+
+func (a *artistRepository) AddArtist(ctx context.Context, artist model.Artist) (int, error) {
+	var id int
+	err := a.db.QueryRow(ctx, insertArtistSQL, artist.Name, artist.Genre, artist.ImageURL).Scan(&id)
+	return id, err
+}
+
+func (a *artistRepository) UpdateArtist(ctx context.Context, artist model.Artist) error {
+	var id int
+	err := a.db.QueryRow(ctx, updateArtistSQL, artist.ID, artist.Name, artist.Genre, artist.ImageURL).Scan(&id)
+	if postgres.IsNoRows(err) {
+		return ErrArtistNotFound
+	}
+	return err
 }
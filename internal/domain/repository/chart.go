@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	refreshChartsSQL =
+	/* sql */ `REFRESH MATERIALIZED VIEW CONCURRENTLY public.charts;`
+
+	// with a genre the positions within the genre are used
+	selectChartSQL =
+	/* sql */ `SELECT
+					entity,
+					id,
+					CASE WHEN $3 = '' THEN position ELSE genre_position END AS place,
+					purchases
+				FROM public.charts
+				WHERE period = $1
+					AND entity = $2
+					AND ($3 = '' OR genre = LOWER($3))
+				ORDER BY place, id
+				LIMIT $4;`
+
+	selectArtistChartPositionsSQL =
+	/* sql */ `SELECT
+					period,
+					position,
+					genre,
+					genre_position,
+					purchases,
+					computed_at
+				FROM public.charts
+				WHERE entity = 'artist' AND id = $1
+				ORDER BY CASE period WHEN 'day' THEN 1 WHEN 'week' THEN 2 ELSE 3 END;`
+)
+
+type ChartRepository interface {
+	RefreshCharts(ctx context.Context) error
+	GetChart(ctx context.Context, period, entity, genre string, limit uint) ([]model.ChartEntry, error)
+	GetArtistChartPositions(ctx context.Context, artistID int) ([]model.ChartPosition, error)
+}
+
+type chartRepository struct {
+	db postgres.Database
+}
+
+func NewChartRepository(db postgres.Database) ChartRepository {
+	return &chartRepository{
+		db: db,
+	}
+}
+
+func (c *chartRepository) RefreshCharts(ctx context.Context) error {
+	return c.db.Exec(ctx, refreshChartsSQL)
+}
+
+// GetChart returns the entries without the artists and albums themselves,
+// only their IDs.
+func (c *chartRepository) GetChart(ctx context.Context, period, entity, genre string, limit uint) ([]model.ChartEntry, error) {
+	rows, err := c.db.Query(ctx, selectChartSQL, period, entity, genre, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]model.ChartEntry, 0)
+
+	for rows.Next() {
+		var entry model.ChartEntry
+		err := rows.Scan(&entry.Entity, &entry.ID, &entry.Position, &entry.Purchases)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+func (c *chartRepository) GetArtistChartPositions(ctx context.Context, artistID int) ([]model.ChartPosition, error) {
+	rows, err := c.db.Query(ctx, selectArtistChartPositionsSQL, artistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]model.ChartPosition, 0)
+
+	for rows.Next() {
+		var position model.ChartPosition
+		err := rows.Scan(&position.Period, &position.Position, &position.Genre, &position.GenrePosition, &position.Purchases, &position.ComputedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, position)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	insertDeadLetterSQL =
+	/* sql */ `INSERT INTO public.dead_letters (topic, message_key, payload, error, attempts, failed_at)
+				VALUES ($1, $2, $3, $4, $5, $6);`
+
+	selectDeadLettersSQL =
+	/* sql */ `SELECT
+					id,
+					topic,
+					message_key,
+					payload,
+					error,
+					attempts,
+					failed_at,
+					republished_at
+				FROM public.dead_letters
+				ORDER BY failed_at DESC
+				LIMIT $2
+				OFFSET $1;`
+
+	selectDeadLettersCountSQL =
+	/* sql */ `SELECT COUNT(*) FROM public.dead_letters;`
+
+	claimDeadLettersSQL =
+	/* sql */ `UPDATE public.dead_letters
+				SET republished_at = NOW()
+				WHERE id = ANY($1) AND republished_at IS NULL
+				RETURNING
+					id,
+					topic,
+					message_key,
+					payload,
+					error,
+					attempts,
+					failed_at,
+					republished_at;`
+
+	releaseDeadLetterSQL =
+	/* sql */ `UPDATE public.dead_letters
+				SET republished_at = NULL
+				WHERE id = $1;`
+)
+
+type DeadLetterRepository interface {
+	AddDeadLetter(ctx context.Context, letter model.DeadLetter) error
+	GetDeadLetters(ctx context.Context, offset, limit uint) ([]model.DeadLetter, error)
+	GetDeadLettersCount(ctx context.Context) (uint, error)
+	ClaimDeadLetters(ctx context.Context, ids []int) ([]model.DeadLetter, error)
+	ReleaseDeadLetter(ctx context.Context, id int) error
+}
+
+type deadLetterRepository struct {
+	db postgres.Database
+}
+
+func NewDeadLetterRepository(db postgres.Database) DeadLetterRepository {
+	return &deadLetterRepository{
+		db: db,
+	}
+}
+
+func (d *deadLetterRepository) AddDeadLetter(ctx context.Context, letter model.DeadLetter) error {
+	return d.db.Exec(ctx, insertDeadLetterSQL, letter.Topic, letter.Key, letter.Payload, letter.Error, letter.Attempts, letter.FailedAt)
+}
+
+func (d *deadLetterRepository) GetDeadLetters(ctx context.Context, offset, limit uint) ([]model.DeadLetter, error) {
+	rows, err := d.db.Query(ctx, selectDeadLettersSQL, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return deadLettersFromRows(rows)
+}
+
+func (d *deadLetterRepository) GetDeadLettersCount(ctx context.Context) (uint, error) {
+	var result uint
+	err := d.db.QueryRow(ctx, selectDeadLettersCountSQL).Scan(&result)
+	return result, err
+}
+
+// ClaimDeadLetters marks the letters which haven't been republished yet as
+// republished and returns them, so that concurrent requests never republish
+// the same letter twice
+func (d *deadLetterRepository) ClaimDeadLetters(ctx context.Context, ids []int) ([]model.DeadLetter, error) {
+	rows, err := d.db.Query(ctx, claimDeadLettersSQL, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return deadLettersFromRows(rows)
+}
+
+// ReleaseDeadLetter gives back a claimed letter that failed to be republished
+func (d *deadLetterRepository) ReleaseDeadLetter(ctx context.Context, id int) error {
+	return d.db.Exec(ctx, releaseDeadLetterSQL, id)
+}
+
+func deadLettersFromRows(rows postgres.Rows) ([]model.DeadLetter, error) {
+	var result []model.DeadLetter
+
+	for rows.Next() {
+		var letter model.DeadLetter
+		err := rows.Scan(&letter.ID, &letter.Topic, &letter.Key, &letter.Payload, &letter.Error, &letter.Attempts, &letter.FailedAt, &letter.RepublishedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, letter)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+var (
+	ErrArtistNotFound    = errors.New("artist not found")
+	ErrAlbumNotFound     = errors.New("album not found")
+	ErrTrackNotFound     = errors.New("track not found")
+	ErrTrackNumberTaken  = errors.New("track with such number already exists in album")
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrAlreadyProcessed  = errors.New("operation has already been processed")
+	ErrUnknownCurrency   = errors.New("unknown currency")
+	ErrCurrencyInUse     = errors.New("currency is used by some users")
+	ErrOperationNotFound = errors.New("operation not found")
+	ErrAlbumPurchased    = errors.New("album is already purchased")
+	ErrAlreadyInWishlist = errors.New("album is already in wishlist")
+	ErrNotInWishlist     = errors.New("album not in wishlist")
+	ErrAlreadyInOrder    = errors.New("album is already in order")
+	ErrNotInOrder        = errors.New("album not in order")
+	ErrInsufficientFunds = errors.New("not enough money on balance")
+	ErrOrderAlreadyPaid  = errors.New("order has already been paid")
+	ErrCurrencyMismatch  = errors.New("album is priced in another currency than the order")
+)
+
+// procedureError turns the errors raised by the order procedures into the
+// sentinel errors above, other errors are returned as they are
+func procedureError(err error) error {
+	switch postgres.ErrorCode(err) {
+	case postgres.AlbumNotFound:
+		return ErrAlbumNotFound
+	case postgres.OrderNotFound:
+		return ErrOrderNotFound
+	case postgres.AlreadyInOrder:
+		return ErrAlreadyInOrder
+	case postgres.NotInOrder:
+		return ErrNotInOrder
+	case postgres.AlreadyOwned:
+		return ErrAlbumPurchased
+	case postgres.InsufficientFunds:
+		return ErrInsufficientFunds
+	case postgres.OrderAlreadyPaid:
+		return ErrOrderAlreadyPaid
+	case postgres.CurrencyMismatch:
+		return ErrCurrencyMismatch
+	default:
+		return err
+	}
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	selectExchangeRatesSQL =
+	/* sql */ `SELECT
+					currency,
+					rate::TEXT,
+					updated_at
+				FROM public.exchange_rates
+				ORDER BY currency;`
+
+	selectExchangeRateSQL =
+	/* sql */ `SELECT
+					currency,
+					rate::TEXT,
+					updated_at
+				FROM public.exchange_rates
+				WHERE currency = $1;`
+
+	upsertExchangeRateSQL =
+	/* sql */ `INSERT INTO public.exchange_rates (currency, rate)
+				VALUES ($1, $2::NUMERIC)
+				ON CONFLICT (currency) DO UPDATE
+				SET rate = EXCLUDED.rate,
+					updated_at = NOW();`
+
+	deleteExchangeRateSQL =
+	/* sql */ `DELETE FROM public.exchange_rates
+				WHERE currency = $1
+				RETURNING currency;`
+)
+
+type ExchangeRateRepository interface {
+	GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error)
+	GetExchangeRate(ctx context.Context, currency string) (model.ExchangeRate, error)
+	SetExchangeRates(ctx context.Context, rates []model.ExchangeRate) error
+	DeleteExchangeRate(ctx context.Context, currency string) error
+}
+
+type exchangeRateRepository struct {
+	db postgres.Database
+}
+
+func NewExchangeRateRepository(db postgres.Database) ExchangeRateRepository {
+	return &exchangeRateRepository{
+		db: db,
+	}
+}
+
+func (e *exchangeRateRepository) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	rows, err := e.db.Query(ctx, selectExchangeRatesSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]model.ExchangeRate, 0)
+
+	for rows.Next() {
+		var rate model.ExchangeRate
+		err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, rate)
+	}
+
+	return result, nil
+}
+
+func (e *exchangeRateRepository) GetExchangeRate(ctx context.Context, currency string) (model.ExchangeRate, error) {
+	var rate model.ExchangeRate
+	err := e.db.QueryRow(ctx, selectExchangeRateSQL, currency).Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+	if postgres.IsNoRows(err) {
+		return model.ExchangeRate{}, ErrUnknownCurrency
+	}
+	return rate, err
+}
+
+// SetExchangeRates adds or replaces all the given rates at once, so a rates
+// file is either loaded completely or not at all.
+func (e *exchangeRateRepository) SetExchangeRates(ctx context.Context, rates []model.ExchangeRate) error {
+	return inTransaction(ctx, e.db, false, func(tx postgres.Transaction) error {
+		for _, rate := range rates {
+			err := tx.Exec(ctx, upsertExchangeRateSQL, rate.Currency, rate.Rate)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (e *exchangeRateRepository) DeleteExchangeRate(ctx context.Context, currency string) error {
+	var deleted string
+	err := e.db.QueryRow(ctx, deleteExchangeRateSQL, currency).Scan(&deleted)
+	switch {
+	case postgres.IsNoRows(err):
+		return ErrUnknownCurrency
+	case postgres.ErrorCode(err) == postgres.ForeignKeyViolation:
+		return ErrCurrencyInUse
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	selectLedgerEntriesSQL =
+	/* sql */ `SELECT
+					id,
+					type,
+					amount,
+					balance_after,
+					currency,
+					order_id,
+					COALESCE(operation_key, ''),
+					created_at
+				FROM public.ledger_entries
+				WHERE user_id = $1
+				ORDER BY created_at DESC, id DESC
+				LIMIT $3
+				OFFSET $2;`
+
+	selectLedgerEntriesCountSQL =
+	/* sql */ `SELECT COUNT(*)
+				FROM public.ledger_entries
+				WHERE user_id = $1;`
+)
+
+type LedgerRepository interface {
+	GetLedgerEntries(ctx context.Context, userID int, offset, limit uint) ([]model.LedgerEntry, error)
+	GetLedgerEntriesCount(ctx context.Context, userID int) (uint, error)
+}
+
+type ledgerRepository struct {
+	db postgres.Database
+}
+
+func NewLedgerRepository(db postgres.Database) LedgerRepository {
+	return &ledgerRepository{
+		db: db,
+	}
+}
+
+func (l *ledgerRepository) GetLedgerEntries(ctx context.Context, userID int, offset, limit uint) ([]model.LedgerEntry, error) {
+	rows, err := l.db.Query(ctx, selectLedgerEntriesSQL, userID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]model.LedgerEntry, 0)
+
+	for rows.Next() {
+		var entry model.LedgerEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Type,
+			&entry.Amount.Amount,
+			&entry.BalanceAfter.Amount,
+			&entry.Amount.Currency,
+			&entry.OrderID,
+			&entry.OperationKey,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.BalanceAfter.Currency = entry.Amount.Currency
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+func (l *ledgerRepository) GetLedgerEntriesCount(ctx context.Context, userID int) (uint, error) {
+	var result uint
+	err := l.db.QueryRow(ctx, selectLedgerEntriesCountSQL, userID).Scan(&result)
+	return result, err
+}
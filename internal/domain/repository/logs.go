@@ -15,11 +15,13 @@ const (
 					u.is_admin,
 					u.nickname,
 					u.balance,
+					u.currency,
 					u.image_url,
 					a.id,
 					a.name,
 					a.image_url,
 					a.price,
+					a.currency,
 					b.logging_time
 				FROM public.buy_logs AS b
 				JOIN public.users AS u ON u.id = b.buyer_id
@@ -65,18 +67,21 @@ func (l *logsRepository) GetLogs(ctx context.Context, offset, limit uint) ([]mod
 			&user.Email,
 			&user.IsAdmin,
 			&user.Nickname,
-			&user.Balance,
+			&user.Balance.Amount,
+			&user.Balance.Currency,
 			&user.ImageURL,
 			&album.ID,
 			&album.Name,
 			&album.ImageURL,
-			&album.Price,
+			&album.Price.Amount,
+			&album.Price.Currency,
 			&log.LoggingTime,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		user.Currency = user.Balance.Currency
 		album.Author = nil
 		log.Buyer = user
 		log.Album = album
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	// a notification saved already is returned instead of a new one
+	insertNotificationSQL =
+	/* sql */ `WITH inserted AS (
+					INSERT INTO public.notifications (message_key, user_id, type, status, order_id, album_id, album_name, amount, currency, created_at)
+					VALUES (NULLIF($1, ''), $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), NULLIF($7, ''), $8, $9, COALESCE($10::TIMESTAMP, NOW()))
+					ON CONFLICT (message_key) DO NOTHING
+					RETURNING id, created_at
+				)
+				SELECT id, created_at FROM inserted
+				UNION ALL
+				SELECT id, created_at
+				FROM public.notifications
+				WHERE message_key = NULLIF($1, '')
+				LIMIT 1;`
+
+	selectNotificationsSQL =
+	/* sql */ `SELECT
+					id,
+					type,
+					status,
+					COALESCE(order_id, 0),
+					COALESCE(album_id, 0),
+					COALESCE(album_name, ''),
+					amount,
+					currency,
+					is_read,
+					created_at
+				FROM public.notifications
+				WHERE user_id = $1 AND (NOT $2 OR is_read = FALSE)
+				ORDER BY id DESC
+				LIMIT $4
+				OFFSET $3;`
+
+	selectNotificationsCountSQL =
+	/* sql */ `SELECT COUNT(*)
+				FROM public.notifications
+				WHERE user_id = $1 AND (NOT $2 OR is_read = FALSE);`
+
+	// the oldest go first, in the order they have happened
+	selectUnreadNotificationsSQL =
+	/* sql */ `SELECT
+					id,
+					type,
+					status,
+					COALESCE(order_id, 0),
+					COALESCE(album_id, 0),
+					COALESCE(album_name, ''),
+					amount,
+					currency,
+					is_read,
+					created_at
+				FROM public.notifications
+				WHERE user_id = $1 AND is_read = FALSE
+				ORDER BY id
+				LIMIT $2;`
+
+	// IDs are handed out before the notifications become visible, one saved
+	// later may have the lower ID, so the unread ones before the given one
+	// are returned as well
+	selectNotificationsAfterSQL =
+	/* sql */ `SELECT
+					id,
+					type,
+					status,
+					COALESCE(order_id, 0),
+					COALESCE(album_id, 0),
+					COALESCE(album_name, ''),
+					amount,
+					currency,
+					is_read,
+					created_at
+				FROM public.notifications
+				WHERE user_id = $1 AND (id > $2 OR is_read = FALSE)
+				ORDER BY id
+				LIMIT $3;`
+
+	updateNotificationsReadSQL =
+	/* sql */ `UPDATE public.notifications
+				SET is_read = TRUE
+				WHERE user_id = $1 AND is_read = FALSE AND (CARDINALITY($2::INT[]) = 0 OR id = ANY($2));`
+)
+
+type NotificationRepository interface {
+	AddNotification(ctx context.Context, notification model.Notification) (model.Notification, error)
+	GetNotifications(ctx context.Context, userID int, unreadOnly bool, offset, limit uint) ([]model.Notification, error)
+	GetNotificationsCount(ctx context.Context, userID int, unreadOnly bool) (uint, error)
+	GetUnreadNotifications(ctx context.Context, userID int, limit uint) ([]model.Notification, error)
+	GetNotificationsAfter(ctx context.Context, userID, afterID int, limit uint) ([]model.Notification, error)
+	MarkNotificationsRead(ctx context.Context, userID int, ids []int) error
+}
+
+type notificationRepository struct {
+	db postgres.Database
+}
+
+func NewNotificationRepository(db postgres.Database) NotificationRepository {
+	return &notificationRepository{
+		db: db,
+	}
+}
+
+// AddNotification saves the notification once per key, the time it has
+// happened at is the current one unless it is set
+func (n *notificationRepository) AddNotification(ctx context.Context, notification model.Notification) (model.Notification, error) {
+	var amount *int64
+	var currency *string
+	if notification.Amount != nil {
+		amount, currency = &notification.Amount.Amount, &notification.Amount.Currency
+	}
+
+	var createdAt *time.Time
+	if !notification.CreatedAt.IsZero() {
+		createdAt = &notification.CreatedAt
+	}
+
+	err := n.db.QueryRow(ctx, insertNotificationSQL, notification.Key, notification.UserID, notification.Type, notification.Status,
+		notification.OrderID, notification.AlbumID, notification.AlbumName, amount, currency, createdAt).
+		Scan(&notification.ID, &notification.CreatedAt)
+	return notification, err
+}
+
+func (n *notificationRepository) GetNotifications(ctx context.Context, userID int, unreadOnly bool, offset, limit uint) ([]model.Notification, error) {
+	rows, err := n.db.Query(ctx, selectNotificationsSQL, userID, unreadOnly, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return notificationsFromRows(rows, userID)
+}
+
+func (n *notificationRepository) GetNotificationsCount(ctx context.Context, userID int, unreadOnly bool) (uint, error) {
+	var result uint
+	err := n.db.QueryRow(ctx, selectNotificationsCountSQL, userID, unreadOnly).Scan(&result)
+	return result, err
+}
+
+func (n *notificationRepository) GetUnreadNotifications(ctx context.Context, userID int, limit uint) ([]model.Notification, error) {
+	rows, err := n.db.Query(ctx, selectUnreadNotificationsSQL, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return notificationsFromRows(rows, userID)
+}
+
+// GetNotificationsAfter returns the notifications that came after the given
+// one along with the unread ones, the oldest first
+func (n *notificationRepository) GetNotificationsAfter(ctx context.Context, userID, afterID int, limit uint) ([]model.Notification, error) {
+	rows, err := n.db.Query(ctx, selectNotificationsAfterSQL, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return notificationsFromRows(rows, userID)
+}
+
+// MarkNotificationsRead marks the given notifications of the user as read,
+// all of them when no IDs are given
+func (n *notificationRepository) MarkNotificationsRead(ctx context.Context, userID int, ids []int) error {
+	if ids == nil {
+		ids = []int{}
+	}
+	return n.db.Exec(ctx, updateNotificationsReadSQL, userID, ids)
+}
+
+func notificationsFromRows(rows postgres.Rows, userID int) ([]model.Notification, error) {
+	result := make([]model.Notification, 0)
+
+	for rows.Next() {
+		var amount *int64
+		var currency *string
+
+		notification := model.Notification{UserID: userID}
+		err := rows.Scan(
+			&notification.ID,
+			&notification.Type,
+			&notification.Status,
+			&notification.OrderID,
+			&notification.AlbumID,
+			&notification.AlbumName,
+			&amount,
+			&currency,
+			&notification.IsRead,
+			&notification.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if amount != nil && currency != nil {
+			money := model.NewMoney(*amount, *currency)
+			notification.Amount = &money
+		}
+
+		result = append(result, notification)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/redis"
+)
+
+// operationLifetime is how long an operation status can be looked up after
+// its last change.
+const operationLifetime = 24 * time.Hour
+
+type OperationRepository interface {
+	// StartOperation records the operation as pending unless a record for it
+	// already exists, which happens when a request is retried with the same
+	// idempotency key. The stored operation is returned in both cases, along
+	// with whether it has been created by this call.
+	StartOperation(ctx context.Context, operation model.Operation) (model.Operation, bool, error)
+	FinishOperation(ctx context.Context, operation model.Operation) error
+	GetOperation(ctx context.Context, userID int, id string) (model.Operation, error)
+}
+
+type operationRepository struct {
+	redis redis.Client
+}
+
+func NewOperationRepository(redis redis.Client) OperationRepository {
+	return &operationRepository{
+		redis: redis,
+	}
+}
+
+func (o *operationRepository) StartOperation(ctx context.Context, operation model.Operation) (model.Operation, bool, error) {
+	operation.Status = model.OperationPending
+	operation.Reason = ""
+	operation.UpdatedAt = time.Now()
+
+	raw, err := json.Marshal(operation)
+	if err != nil {
+		return model.Operation{}, false, err
+	}
+
+	created, err := o.redis.SetNX(ctx, operationKey(operation.UserID, operation.ID), raw, operationLifetime)
+	if err != nil {
+		return model.Operation{}, false, err
+	}
+	if created {
+		return operation, true, nil
+	}
+
+	stored, err := o.GetOperation(ctx, operation.UserID, operation.ID)
+	return stored, false, err
+}
+
+func (o *operationRepository) FinishOperation(ctx context.Context, operation model.Operation) error {
+	operation.UpdatedAt = time.Now()
+
+	raw, err := json.Marshal(operation)
+	if err != nil {
+		return err
+	}
+
+	return o.redis.Set(ctx, operationKey(operation.UserID, operation.ID), raw, operationLifetime)
+}
+
+func (o *operationRepository) GetOperation(ctx context.Context, userID int, id string) (model.Operation, error) {
+	raw, err := o.redis.Get(ctx, operationKey(userID, id))
+	if err != nil {
+		if err == redis.ErrNotFound {
+			return model.Operation{}, ErrOperationNotFound
+		}
+		return model.Operation{}, err
+	}
+
+	var operation model.Operation
+	err = json.Unmarshal([]byte(raw), &operation)
+	if err != nil {
+		return model.Operation{}, err
+	}
+	operation.UserID = userID
+	return operation, nil
+}
+
+func operationKey(userID int, id string) string {
+	return fmt.Sprintf("operation:%d:%s", userID, id)
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	"github.com/allnightmarel0Ng/albums/internal/domain/model"
@@ -22,10 +21,16 @@ const (
 					u.is_admin,
 					u.nickname,
 					u.balance,
+					u.currency,
 					u.image_url,
 					o.date,
 					o.total_price,
+					o.currency,
 					o.is_paid,
+					o.is_refunded,
+					o.paid_amount,
+					o.paid_currency,
+					COALESCE(o.exchange_rate::TEXT, ''),
 					a.id,
 					a.name,
 					ar.id,
@@ -33,7 +38,8 @@ const (
 					ar.genre,
 					ar.image_url,
 					a.image_url,
-					a.price
+					a.price,
+					a.currency
 				FROM public.orders AS o
 				JOIN public.users AS u ON o.user_id = u.id
 				JOIN public.order_items AS oi ON oi.order_id = o.id
@@ -42,12 +48,35 @@ const (
 				WHERE u.id = $1`
 	isPaidFilterSQL = " AND o.is_paid = FALSE"
 	orderSQL        = "\tORDER BY o.is_paid, o.id"
+
+	selectOrderByIDSQL =
+	/* sql */ `SELECT
+					id,
+					COALESCE(user_id, 0),
+					date,
+					total_price,
+					currency,
+					is_paid,
+					is_refunded,
+					paid_amount,
+					paid_currency,
+					COALESCE(exchange_rate::TEXT, '')
+				FROM public.orders
+				WHERE id = $1;`
+
+	deleteUnpaidOrderSQL =
+	/* sql */ `DELETE
+				FROM public.orders
+				WHERE user_id = $1 AND is_paid = FALSE
+				RETURNING id;`
 )
 
 type OrderRepository interface {
 	AddAlbumToUserOrder(ctx context.Context, userID, albumID int) error
 	DeleteAlbumFromUserOrder(ctx context.Context, userID, albumID int) error
 	GetUserOrders(ctx context.Context, userID int, unpaidOnly bool) ([]model.Order, error)
+	GetOrderByID(ctx context.Context, orderID int) (model.Order, error)
+	CancelUnpaidOrder(ctx context.Context, userID int) (int, error)
 }
 
 type orderRepository struct {
@@ -61,11 +90,11 @@ func NewOrderRepository(db postgres.Database) OrderRepository {
 }
 
 func (o *orderRepository) AddAlbumToUserOrder(ctx context.Context, userID, albumID int) error {
-	return callWillSerialization(o.db, ctx, callAddAlbumProcedureSQL, userID, albumID)
+	return callProcedure(ctx, o.db, callAddAlbumProcedureSQL, userID, albumID)
 }
 
 func (o *orderRepository) DeleteAlbumFromUserOrder(ctx context.Context, userID, albumID int) error {
-	return callWillSerialization(o.db, ctx, callDeleteAlbumProcedureSQL, userID, albumID)
+	return callProcedure(ctx, o.db, callDeleteAlbumProcedureSQL, userID, albumID)
 }
 
 func (o *orderRepository) GetUserOrders(ctx context.Context, userID int, unpaidOnly bool) ([]model.Order, error) {
@@ -102,16 +131,21 @@ func (o *orderRepository) GetUserOrders(ctx context.Context, userID int, unpaidO
 
 	for rows.Next() {
 		var (
-			order  model.Order
-			album  model.Album
-			author model.Artist
+			order        model.Order
+			album        model.Album
+			author       model.Artist
+			paidAmount   *int64
+			paidCurrency *string
 		)
 
-		err := rows.Scan(&order.ID, &order.Orderer.ID, &order.Orderer.Email, &order.Orderer.IsAdmin, &order.Orderer.Nickname, &order.Orderer.Balance, &order.Orderer.ImageURL, &order.Date, &order.TotalPrice, &order.IsPaid, &album.ID, &album.Name, &author.ID, &author.Name, &author.Genre, &author.ImageURL, &album.ImageURL, &album.Price)
+		err := rows.Scan(&order.ID, &order.Orderer.ID, &order.Orderer.Email, &order.Orderer.IsAdmin, &order.Orderer.Nickname, &order.Orderer.Balance.Amount, &order.Orderer.Balance.Currency, &order.Orderer.ImageURL, &order.Date, &order.TotalPrice.Amount, &order.TotalPrice.Currency, &order.IsPaid, &order.IsRefunded, &paidAmount, &paidCurrency, &order.ExchangeRate, &album.ID, &album.Name, &author.ID, &author.Name, &author.Genre, &author.ImageURL, &album.ImageURL, &album.Price.Amount, &album.Price.Currency)
 		if err != nil {
 			return nil, err
 		}
 
+		order.Orderer.Currency = order.Orderer.Balance.Currency
+		order.PaidAmount = paidMoney(paidAmount, paidCurrency)
+
 		album.Author = &author
 
 		_, ok := ordersMap[order.ID]
@@ -133,26 +167,36 @@ func (o *orderRepository) GetUserOrders(ctx context.Context, userID int, unpaidO
 	return result, nil
 }
 
-func callWillSerialization(db postgres.Database, ctx context.Context, sql string, params ...interface{}) error {
-	tx, err := db.Begin(ctx)
-	if err != nil {
-		return err
+func (o *orderRepository) GetOrderByID(ctx context.Context, orderID int) (model.Order, error) {
+	var (
+		order        model.Order
+		paidAmount   *int64
+		paidCurrency *string
+	)
+
+	err := o.db.QueryRow(ctx, selectOrderByIDSQL, orderID).Scan(&order.ID, &order.Orderer.ID, &order.Date, &order.TotalPrice.Amount, &order.TotalPrice.Currency, &order.IsPaid, &order.IsRefunded, &paidAmount, &paidCurrency, &order.ExchangeRate)
+	if postgres.IsNoRows(err) {
+		return model.Order{}, ErrOrderNotFound
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("recovered from panic! %v", r)
-		}
+	order.PaidAmount = paidMoney(paidAmount, paidCurrency)
+	return order, err
+}
 
-		if err == nil {
-			err = tx.Commit(ctx)
-		} else {
-			err = tx.Rollback(ctx)
-		}
-	}()
+func (o *orderRepository) CancelUnpaidOrder(ctx context.Context, userID int) (int, error) {
+	var id int
+	err := o.db.QueryRow(ctx, deleteUnpaidOrderSQL, userID).Scan(&id)
+	if postgres.IsNoRows(err) {
+		return 0, ErrOrderNotFound
+	}
+	return id, err
+}
+
+func paidMoney(amount *int64, currency *string) *model.Money {
+	if amount == nil || currency == nil {
+		return nil
+	}
 
-	err = tx.Exec(ctx, "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE;")
-	err = tx.Exec(ctx, sql, params...)
-	err = tx.Commit(ctx)
-	return err
+	result := model.NewMoney(*amount, *currency)
+	return &result
 }
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	refreshCoPurchasesSQL =
+	/* sql */ `REFRESH MATERIALIZED VIEW CONCURRENTLY public.album_co_purchases;`
+
+	// albumScoresSQL scores the albums the user $1 doesn't own yet. Albums
+	// bought along with the ones the user owns weigh the most, then albums by
+	// the artists the user has bought, then albums of the genres the user has
+	// bought.
+	albumScoresSQL =
+	/* sql */ `WITH owned AS (
+					SELECT DISTINCT pa.album_id, a.artist_id, LOWER(ar.genre) AS genre
+					FROM public.purchased_albums AS pa
+					JOIN public.albums AS a ON a.id = pa.album_id
+					JOIN public.artists AS ar ON ar.id = a.artist_id
+					WHERE pa.user_id = $1
+				), scores AS (
+					SELECT cp.other_album_id, SUM(cp.buyers) * 3.0 AS score, 'co-purchase' AS reason
+					FROM owned
+					JOIN public.album_co_purchases AS cp ON cp.album_id = owned.album_id
+					GROUP BY cp.other_album_id
+					UNION ALL
+					SELECT a.id, COUNT(*) * 2.0, 'artist'
+					FROM owned
+					JOIN public.albums AS a ON a.artist_id = owned.artist_id
+					GROUP BY a.id
+					UNION ALL
+					SELECT a.id, COUNT(*) * 1.0, 'genre'
+					FROM owned
+					JOIN public.artists AS ar ON LOWER(ar.genre) = owned.genre
+					JOIN public.albums AS a ON a.artist_id = ar.id
+					GROUP BY a.id
+				), album_scores AS (
+					SELECT
+						s.album_id,
+						SUM(s.score)::DOUBLE PRECISION AS score,
+						(ARRAY_AGG(s.reason ORDER BY s.score DESC))[1] AS reason
+					FROM scores AS s
+					WHERE s.album_id IS NOT NULL
+						AND s.album_id NOT IN (SELECT album_id FROM owned)
+					GROUP BY s.album_id
+				)
+				`
+
+	recommendAlbumsSQL = albumScoresSQL +
+		/* sql */ `SELECT album_id, reason, score
+				FROM album_scores
+				ORDER BY score DESC, album_id DESC
+				LIMIT $2;`
+
+	// an artist is as good as the sum of its not yet owned albums, artists
+	// the user has bought from are left out
+	recommendArtistsSQL = albumScoresSQL +
+		/* sql */ `SELECT a.artist_id, (ARRAY_AGG(s.reason ORDER BY s.score DESC))[1], SUM(s.score)
+				FROM album_scores AS s
+				JOIN public.albums AS a ON a.id = s.album_id
+				WHERE a.artist_id IS NOT NULL
+					AND a.artist_id NOT IN (SELECT artist_id FROM owned)
+				GROUP BY a.artist_id
+				ORDER BY SUM(s.score) DESC, a.artist_id DESC
+				LIMIT $2;`
+
+	// popularity is the number of purchases in the monthly chart, the newest
+	// albums which haven't been bought this month follow the chart
+	popularAlbumsSQL =
+	/* sql */ `SELECT id, 'popular', score
+				FROM (
+					(SELECT c.id, c.purchases::DOUBLE PRECISION AS score, 0 AS tier, c.position AS place
+					FROM public.charts AS c
+					WHERE c.period = 'month' AND c.entity = 'album'
+						AND NOT EXISTS (
+							SELECT 1
+							FROM public.purchased_albums AS pa
+							WHERE pa.user_id = $1 AND pa.album_id = c.id
+						)
+					ORDER BY c.position, c.id DESC
+					LIMIT $2)
+					UNION ALL
+					(SELECT a.id, 0, 1, -a.id
+					FROM public.albums AS a
+					WHERE NOT EXISTS (
+							SELECT 1
+							FROM public.charts AS c
+							WHERE c.period = 'month' AND c.entity = 'album' AND c.id = a.id
+						)
+						AND NOT EXISTS (
+							SELECT 1
+							FROM public.purchased_albums AS pa
+							WHERE pa.user_id = $1 AND pa.album_id = a.id
+						)
+					ORDER BY a.id DESC
+					LIMIT $2)
+				) AS popular
+				ORDER BY tier, place
+				LIMIT $2;`
+
+	popularArtistsSQL =
+	/* sql */ `SELECT id, 'popular', score
+				FROM (
+					(SELECT c.id, c.purchases::DOUBLE PRECISION AS score, 0 AS tier, c.position AS place
+					FROM public.charts AS c
+					WHERE c.period = 'month' AND c.entity = 'artist'
+					ORDER BY c.position, c.id DESC
+					LIMIT $1)
+					UNION ALL
+					(SELECT ar.id, 0, 1, -ar.id
+					FROM public.artists AS ar
+					WHERE NOT EXISTS (
+						SELECT 1
+						FROM public.charts AS c
+						WHERE c.period = 'month' AND c.entity = 'artist' AND c.id = ar.id
+					)
+					ORDER BY ar.id DESC
+					LIMIT $1)
+				) AS popular
+				ORDER BY tier, place
+				LIMIT $1;`
+)
+
+type RecommendationRepository interface {
+	RecommendAlbums(ctx context.Context, userID int, limit uint) ([]model.Recommendation, error)
+	RecommendArtists(ctx context.Context, userID int, limit uint) ([]model.Recommendation, error)
+	// PopularAlbums leaves out the albums the user already owns, userID is 0
+	// for anonymous users.
+	PopularAlbums(ctx context.Context, userID int, limit uint) ([]model.Recommendation, error)
+	PopularArtists(ctx context.Context, limit uint) ([]model.Recommendation, error)
+	RefreshCoPurchases(ctx context.Context) error
+}
+
+type recommendationRepository struct {
+	db postgres.Database
+}
+
+func NewRecommendationRepository(db postgres.Database) RecommendationRepository {
+	return &recommendationRepository{
+		db: db,
+	}
+}
+
+func (r *recommendationRepository) RecommendAlbums(ctx context.Context, userID int, limit uint) ([]model.Recommendation, error) {
+	return r.recommendations(ctx, model.SearchEntityAlbum, recommendAlbumsSQL, userID, limit)
+}
+
+func (r *recommendationRepository) RecommendArtists(ctx context.Context, userID int, limit uint) ([]model.Recommendation, error) {
+	return r.recommendations(ctx, model.SearchEntityArtist, recommendArtistsSQL, userID, limit)
+}
+
+func (r *recommendationRepository) PopularAlbums(ctx context.Context, userID int, limit uint) ([]model.Recommendation, error) {
+	return r.recommendations(ctx, model.SearchEntityAlbum, popularAlbumsSQL, userID, limit)
+}
+
+func (r *recommendationRepository) PopularArtists(ctx context.Context, limit uint) ([]model.Recommendation, error) {
+	return r.recommendations(ctx, model.SearchEntityArtist, popularArtistsSQL, limit)
+}
+
+func (r *recommendationRepository) RefreshCoPurchases(ctx context.Context) error {
+	return r.db.Exec(ctx, refreshCoPurchasesSQL)
+}
+
+func (r *recommendationRepository) recommendations(ctx context.Context, entity string, sql string, args ...interface{}) ([]model.Recommendation, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]model.Recommendation, 0)
+
+	for rows.Next() {
+		recommendation := model.Recommendation{Entity: entity}
+		err := rows.Scan(&recommendation.ID, &recommendation.Reason, &recommendation.Score)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, recommendation)
+	}
+
+	return result, nil
+}
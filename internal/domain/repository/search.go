@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/allnightmarel0Ng/albums/internal/domain/model"
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	// $1 is a prefix tsquery built by prefixQuery and $2 is the raw query
+	// used for the trigram similarity, which makes typos still match. Every
+	// entity is reported once, by the field that ranks best. Without a query
+	// every artist and album passing the filters is a result.
+	//
+	// The sort key, its type and the direction are filled in from
+	// searchOrders, the cursor ($8, $9, $10) is the last result of the
+	// previous page.
+	searchSQLTemplate =
+	/* sql */ `WITH query AS (
+					SELECT to_tsquery('simple', $1) AS q
+				), hits AS (
+					SELECT 'artist' AS entity, ar.id, 'name' AS field, ar.name AS value,
+						ts_rank(ar.name_vector, query.q) + word_similarity($2, ar.name) AS rank
+					FROM public.artists AS ar, query
+					WHERE $2 <> '' AND (ar.name_vector @@ query.q OR $2 <%% ar.name)
+					UNION ALL
+					SELECT 'artist', ar.id, 'genre', ar.genre,
+						(ts_rank(ar.genre_vector, query.q) + word_similarity($2, ar.genre)) * 0.5
+					FROM public.artists AS ar, query
+					WHERE $2 <> '' AND (ar.genre_vector @@ query.q OR $2 <%% ar.genre)
+					UNION ALL
+					SELECT 'album', a.id, 'name', a.name,
+						ts_rank(a.name_vector, query.q) + word_similarity($2, a.name)
+					FROM public.albums AS a, query
+					WHERE $2 <> '' AND (a.name_vector @@ query.q OR $2 <%% a.name)
+					UNION ALL
+					SELECT 'album', t.album_id, 'track', t.name,
+						(ts_rank(t.name_vector, query.q) + word_similarity($2, t.name)) * 0.8
+					FROM public.tracks AS t, query
+					WHERE $2 <> '' AND t.album_id IS NOT NULL AND (t.name_vector @@ query.q OR $2 <%% t.name)
+				), best AS (
+					SELECT DISTINCT ON (entity, id) entity, id, field, value, rank
+					FROM hits
+					ORDER BY entity, id, rank DESC
+				), candidates AS (
+					SELECT 'artist' AS entity, ar.id, ar.name, NULL::BIGINT AS price, ar.created_at,
+						COALESCE(b.field, '') AS field, COALESCE(b.value, '') AS value,
+						COALESCE(b.rank, 0)::DOUBLE PRECISION AS rank
+					FROM public.artists AS ar
+					LEFT JOIN best AS b ON b.entity = 'artist' AND b.id = ar.id
+					WHERE ($2 = '' OR b.id IS NOT NULL)
+						AND ($3 = '' OR LOWER(ar.genre) = LOWER($3))
+						AND ($6 = 0 OR ar.id = $6)
+						AND $4::BIGINT IS NULL AND $5::BIGINT IS NULL AND $7 = 0
+					UNION ALL
+					SELECT 'album', a.id, a.name, a.price, a.created_at,
+						COALESCE(b.field, ''), COALESCE(b.value, ''),
+						COALESCE(b.rank, 0)::DOUBLE PRECISION
+					FROM public.albums AS a
+					JOIN public.artists AS ar ON a.artist_id = ar.id
+					LEFT JOIN best AS b ON b.entity = 'album' AND b.id = a.id
+					WHERE ($2 = '' OR b.id IS NOT NULL)
+						AND ($3 = '' OR LOWER(ar.genre) = LOWER($3))
+						AND ($4::BIGINT IS NULL OR a.price >= $4)
+						AND ($5::BIGINT IS NULL OR a.price <= $5)
+						AND ($6 = 0 OR a.artist_id = $6)
+						AND ($7 = 0 OR NOT EXISTS (
+							SELECT 1
+							FROM public.purchased_albums AS pu
+							WHERE pu.user_id = $7 AND pu.album_id = a.id
+						))
+				)
+				SELECT entity, id, field, value, rank, (%[1]s)::TEXT
+				FROM candidates
+				WHERE $8::TEXT IS NULL
+					OR %[1]s %[2]s $8::%[3]s
+					OR (%[1]s = $8::%[3]s AND (entity, id) > ($9::TEXT, $10::INT))
+				ORDER BY %[1]s %[4]s, entity, id
+				LIMIT $11;`
+
+	selectSuggestionsSQL =
+	/* sql */ `SELECT 'artist', id, name, 0
+				FROM public.artists
+				UNION ALL
+				SELECT 'album', id, name, 0
+				FROM public.albums
+				UNION ALL
+				SELECT 'track', id, name, album_id
+				FROM public.tracks
+				WHERE album_id IS NOT NULL;`
+)
+
+type searchOrder struct {
+	key     string
+	keyType string
+	desc    bool
+}
+
+// searchOrders maps every model.SearchSort* to the expression the results are
+// ordered by. Artists have no price, so they go after albums in both price
+// orders.
+var searchOrders = map[string]searchOrder{
+	model.SearchSortRelevance: {key: "rank", keyType: "DOUBLE PRECISION", desc: true},
+	model.SearchSortName:      {key: "LOWER(name)", keyType: "TEXT"},
+	model.SearchSortPrice:     {key: "COALESCE(price, 9223372036854775807)", keyType: "BIGINT"},
+	model.SearchSortPriceDesc: {key: "COALESCE(price, -1)", keyType: "BIGINT", desc: true},
+	model.SearchSortNewest:    {key: "created_at", keyType: "TIMESTAMP", desc: true},
+}
+
+func (s searchOrder) sql() string {
+	comparison, direction := ">", "ASC"
+	if s.desc {
+		comparison, direction = "<", "DESC"
+	}
+	return fmt.Sprintf(searchSQLTemplate, s.key, comparison, s.keyType, direction)
+}
+
+type SearchRepository interface {
+	Search(ctx context.Context, params model.SearchParams) ([]model.SearchMatch, error)
+	GetSuggestions(ctx context.Context) ([]model.Suggestion, error)
+}
+
+type searchRepository struct {
+	db postgres.Database
+}
+
+func NewSearchRepository(db postgres.Database) SearchRepository {
+	return &searchRepository{
+		db: db,
+	}
+}
+
+func (s *searchRepository) Search(ctx context.Context, params model.SearchParams) ([]model.SearchMatch, error) {
+	order, ok := searchOrders[params.Sort]
+	if !ok {
+		order = searchOrders[model.SearchSortRelevance]
+	}
+
+	var minPrice, maxPrice *int64
+	if params.MinPrice != nil {
+		minPrice = &params.MinPrice.Amount
+	}
+	if params.MaxPrice != nil {
+		maxPrice = &params.MaxPrice.Amount
+	}
+
+	var (
+		afterKey    *string
+		afterEntity string
+		afterID     int
+	)
+	if params.After != nil {
+		afterKey = &params.After.SortKey
+		afterEntity = params.After.Entity
+		afterID = params.After.ID
+	}
+
+	query := strings.ToLower(strings.TrimSpace(params.Query))
+	rows, err := s.db.Query(ctx, order.sql(),
+		prefixQuery(query), query, params.Genre, minPrice, maxPrice, params.ArtistID, params.NotPurchasedBy,
+		afterKey, afterEntity, afterID, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]model.SearchMatch, 0)
+
+	for rows.Next() {
+		var match model.SearchMatch
+		err := rows.Scan(&match.Entity, &match.ID, &match.Field, &match.Value, &match.Rank, &match.SortKey)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, match)
+	}
+
+	return result, nil
+}
+
+// GetSuggestions returns the names of all artists, albums and tracks.
+func (s *searchRepository) GetSuggestions(ctx context.Context) ([]model.Suggestion, error) {
+	rows, err := s.db.Query(ctx, selectSuggestionsSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]model.Suggestion, 0)
+
+	for rows.Next() {
+		var suggestion model.Suggestion
+		err := rows.Scan(&suggestion.Kind, &suggestion.ID, &suggestion.Name, &suggestion.AlbumID)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, suggestion)
+	}
+
+	return result, nil
+}
+
+// prefixQuery turns the user's query into a tsquery where every word has to
+// match as a prefix, e.g. "dark sid" becomes "dark:* & sid:*". Everything
+// except letters and digits separates words, so the query can't contain
+// tsquery operators.
+func prefixQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
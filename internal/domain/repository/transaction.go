@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	serializableSQL = /* sql */ `SET TRANSACTION ISOLATION LEVEL SERIALIZABLE;`
+
+	serializationAttempts = 3
+	serializationBackoff  = 50 * time.Millisecond
+)
+
+func inTransaction(ctx context.Context, db postgres.Database, serializable bool, callback func(tx postgres.Transaction) error) (err error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered from panic: %v", r)
+		}
+
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	if serializable {
+		err = tx.Exec(ctx, serializableSQL)
+		if err != nil {
+			return err
+		}
+	}
+
+	return callback(tx)
+}
+
+// inSerializableTransaction runs the callback in a serializable transaction
+// and starts it over when it loses to a concurrent one
+func inSerializableTransaction(ctx context.Context, db postgres.Database, callback func(tx postgres.Transaction) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = inTransaction(ctx, db, true, callback)
+		if !postgres.IsSerializationFailure(err) || attempt == serializationAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * serializationBackoff):
+		}
+	}
+}
+
+// callProcedure calls an order procedure in a serializable transaction
+func callProcedure(ctx context.Context, db postgres.Database, sql string, params ...interface{}) error {
+	return procedureError(inSerializableTransaction(ctx, db, func(tx postgres.Transaction) error {
+		return tx.Exec(ctx, sql, params...)
+	}))
+}
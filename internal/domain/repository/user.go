@@ -24,21 +24,28 @@ const (
 					is_admin,
 					nickname,
 					balance,
+					currency,
 					image_url
 				FROM public.users
 				WHERE id = $1;`
 
-	updateBalanceSQL =
-	/* sql */ `UPDATE public.users
-				SET balance = balance + ($1)
-				WHERE id = $2;`
+	depositSQL =
+	/* sql */ `SELECT apply_ledger_entry(u.id, $2, convert_amount($3, $4, u.currency), u.currency, NULL, $5)
+				FROM public.users AS u
+				WHERE u.id = $1;`
 
 	callPayForOrderSQL =
-	/* sql */ `CALL pay_for_order($1, $2);`
+	/* sql */ `CALL pay_for_order($1, $2, $3);`
+
+	callRefundOrderSQL =
+	/* sql */ `CALL refund_order($1, $2, $3);`
+
+	callChangeUserCurrencySQL =
+	/* sql */ `CALL change_user_currency($1, $2);`
 
 	insertNewUserSQL =
-	/* sql */ `INSERT INTO public.users (email, is_admin, nickname, image_url)
-				VALUES ($1, $2, $3, $4)
+	/* sql */ `INSERT INTO public.users (email, is_admin, nickname, image_url, currency)
+				VALUES ($1, $2, $3, $4, $5)
 				RETURNING id;`
 
 	insertNewCredentialSQL =
@@ -53,6 +60,12 @@ const (
 						ELSE FALSE 
 					END AS email_exists;`
 
+	insertProcessedOperationSQL =
+	/* sql */ `INSERT INTO public.processed_operations (user_id, operation_key)
+				VALUES ($1, $2)
+				ON CONFLICT (user_id, operation_key) DO NOTHING
+				RETURNING id;`
+
 	selectAlbumOwnersIdsSQL =
 	/* sql */ `SELECT user_id
 				FROM public.purchased_albums
@@ -62,11 +75,13 @@ const (
 type UserRepository interface {
 	GetIDPasswordHash(ctx context.Context, email string) (int, string, bool, error)
 	GetUser(ctx context.Context, id int) (model.User, error)
-	ChangeBalance(ctx context.Context, id int, diff uint) error
-	PayForOrder(ctx context.Context, userID int, orderID int) error
-	AddNewUser(ctx context.Context, email, password_hash string, isAdmin bool, nickname, imageURL string) error
+	ChangeBalance(ctx context.Context, id int, diff model.Money, operationKey string) error
+	PayForOrder(ctx context.Context, userID int, orderID int, operationKey string) error
+	RefundOrder(ctx context.Context, userID int, orderID int, operationKey string) error
+	AddNewUser(ctx context.Context, email, password_hash string, isAdmin bool, nickname, imageURL, currency string) error
 	FindUserByEmail(ctx context.Context, email string) (bool, error)
 	GetAlbumOwnersIds(ctx context.Context, albumID int) ([]int, error)
+	ChangeCurrency(ctx context.Context, id int, currency string) error
 }
 
 type userRepository struct {
@@ -93,23 +108,55 @@ func (u *userRepository) GetIDPasswordHash(ctx context.Context, email string) (i
 func (u *userRepository) GetUser(ctx context.Context, id int) (model.User, error) {
 	var result model.User
 
-	err := u.db.QueryRow(ctx, selectUserByEmailSQL, id).Scan(&result.ID, &result.Email, &result.IsAdmin, &result.Nickname, &result.Balance, &result.ImageURL)
+	err := u.db.QueryRow(ctx, selectUserByEmailSQL, id).Scan(&result.ID, &result.Email, &result.IsAdmin, &result.Nickname, &result.Balance.Amount, &result.Balance.Currency, &result.ImageURL)
 	if err != nil {
 		return model.User{}, err
 	}
 
+	result.Currency = result.Balance.Currency
 	return result, err
 }
 
-func (u *userRepository) ChangeBalance(ctx context.Context, id int, diff uint) error {
-	return u.db.Exec(ctx, updateBalanceSQL, diff, id)
+func (u *userRepository) ChangeBalance(ctx context.Context, id int, diff model.Money, operationKey string) error {
+	return inTransaction(ctx, u.db, false, func(tx postgres.Transaction) error {
+		err := markProcessed(ctx, tx, id, operationKey)
+		if err != nil {
+			return err
+		}
+
+		// money deposited in another currency is converted to the one of the balance
+		return tx.Exec(ctx, depositSQL, id, model.LedgerDeposit, diff.Amount, diff.Currency, operationKey)
+	})
+}
+
+func (u *userRepository) PayForOrder(ctx context.Context, userID int, orderID int, operationKey string) error {
+	err := inSerializableTransaction(ctx, u.db, func(tx postgres.Transaction) error {
+		err := markProcessed(ctx, tx, userID, operationKey)
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(ctx, callPayForOrderSQL, userID, orderID, operationKey)
+	})
+	// the purchased_albums constraint catches owners the procedure has missed
+	if postgres.ErrorCode(err) == postgres.UniqueViolation {
+		return ErrAlbumPurchased
+	}
+	return procedureError(err)
 }
 
-func (u *userRepository) PayForOrder(ctx context.Context, userID int, orderID int) error {
-	return callWillSerialization(u.db, ctx, callPayForOrderSQL, userID, orderID)
+func (u *userRepository) RefundOrder(ctx context.Context, userID int, orderID int, operationKey string) error {
+	return inTransaction(ctx, u.db, false, func(tx postgres.Transaction) error {
+		err := markProcessed(ctx, tx, userID, operationKey)
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(ctx, callRefundOrderSQL, userID, orderID, operationKey)
+	})
 }
 
-func (u *userRepository) AddNewUser(ctx context.Context, email, password_hash string, isAdmin bool, nickname, imageURL string) error {
+func (u *userRepository) AddNewUser(ctx context.Context, email, password_hash string, isAdmin bool, nickname, imageURL, currency string) error {
 	tx, err := u.db.Begin(ctx)
 	if err != nil {
 		return err
@@ -128,7 +175,10 @@ func (u *userRepository) AddNewUser(ctx context.Context, email, password_hash st
 	}()
 
 	var id int
-	err = tx.QueryRow(ctx, insertNewUserSQL, email, isAdmin, nickname, imageURL).Scan(&id)
+	err = tx.QueryRow(ctx, insertNewUserSQL, email, isAdmin, nickname, imageURL, currency).Scan(&id)
+	if postgres.ErrorCode(err) == postgres.ForeignKeyViolation {
+		return ErrUnknownCurrency
+	}
 	if err != nil {
 		return err
 	}
@@ -162,3 +212,18 @@ func (u *userRepository) GetAlbumOwnersIds(ctx context.Context, albumID int) ([]
 	}
 	return result, nil
 }
+
+// ChangeCurrency converts the balance of the user to the new currency at the
+// current rate, recording both sides of the conversion in the ledger.
+func (u *userRepository) ChangeCurrency(ctx context.Context, id int, currency string) error {
+	return u.db.Exec(ctx, callChangeUserCurrencySQL, id, currency)
+}
+
+func markProcessed(ctx context.Context, tx postgres.Transaction, userID int, operationKey string) error {
+	var id int
+	err := tx.QueryRow(ctx, insertProcessedOperationSQL, userID, operationKey).Scan(&id)
+	if postgres.IsNoRows(err) {
+		return ErrAlreadyProcessed
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/allnightmarel0Ng/albums/internal/infrastructure/postgres"
+)
+
+const (
+	selectIsAlbumPurchasedSQL =
+	/* sql */ `SELECT EXISTS (
+					SELECT 1
+					FROM public.purchased_albums
+					WHERE user_id = $1 AND album_id = $2
+				);`
+
+	insertWishlistItemSQL =
+	/* sql */ `INSERT INTO public.wishlist_items (user_id, album_id)
+				VALUES ($1, $2)
+				ON CONFLICT (user_id, album_id) DO NOTHING
+				RETURNING id;`
+
+	deleteWishlistItemSQL =
+	/* sql */ `DELETE FROM public.wishlist_items
+				WHERE user_id = $1 AND album_id = $2
+				RETURNING id;`
+
+	selectWishlistAlbumIDsSQL =
+	/* sql */ `SELECT w.album_id
+				FROM public.wishlist_items AS w
+				WHERE w.user_id = $1
+					AND NOT EXISTS (
+						SELECT 1
+						FROM public.purchased_albums AS pa
+						WHERE pa.user_id = w.user_id AND pa.album_id = w.album_id
+					)
+				ORDER BY w.added_at DESC, w.id DESC;`
+
+	callMoveWishlistToOrderProcedureSQL =
+	/* sql */ `CALL move_wishlist_to_order($1);`
+)
+
+type WishlistRepository interface {
+	AddToWishlist(ctx context.Context, userID, albumID int) error
+	RemoveFromWishlist(ctx context.Context, userID, albumID int) error
+	GetWishlistAlbumIDs(ctx context.Context, userID int) ([]int, error)
+	MoveWishlistToOrder(ctx context.Context, userID int) error
+}
+
+type wishlistRepository struct {
+	db postgres.Database
+}
+
+func NewWishlistRepository(db postgres.Database) WishlistRepository {
+	return &wishlistRepository{
+		db: db,
+	}
+}
+
+func (w *wishlistRepository) AddToWishlist(ctx context.Context, userID, albumID int) error {
+	var purchased bool
+	err := w.db.QueryRow(ctx, selectIsAlbumPurchasedSQL, userID, albumID).Scan(&purchased)
+	if err != nil {
+		return err
+	}
+
+	if purchased {
+		return ErrAlbumPurchased
+	}
+
+	var id int
+	err = w.db.QueryRow(ctx, insertWishlistItemSQL, userID, albumID).Scan(&id)
+	switch {
+	case postgres.IsNoRows(err):
+		return ErrAlreadyInWishlist
+	case postgres.ErrorCode(err) == postgres.ForeignKeyViolation:
+		return ErrAlbumNotFound
+	}
+	return err
+}
+
+func (w *wishlistRepository) RemoveFromWishlist(ctx context.Context, userID, albumID int) error {
+	var id int
+	err := w.db.QueryRow(ctx, deleteWishlistItemSQL, userID, albumID).Scan(&id)
+	if postgres.IsNoRows(err) {
+		return ErrNotInWishlist
+	}
+	return err
+}
+
+// GetWishlistAlbumIDs returns the wishlist from the latest additions, albums
+// the user has bought since adding them are left out
+func (w *wishlistRepository) GetWishlistAlbumIDs(ctx context.Context, userID int) ([]int, error) {
+	rows, err := w.db.Query(ctx, selectWishlistAlbumIDsSQL, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return idsFromRows(rows)
+}
+
+// MoveWishlistToOrder adds the wishlist albums which are neither bought nor
+// ordered yet to the unpaid order and clears the wishlist
+func (w *wishlistRepository) MoveWishlistToOrder(ctx context.Context, userID int) error {
+	return callProcedure(ctx, w.db, callMoveWishlistToOrderProcedureSQL, userID)
+}
@@ -1,22 +1,55 @@
 package kafka
 
 import (
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
+// rewindDelay is how long a consumer waits before reading a message it has
+// failed to handle again
+const rewindDelay = time.Second
+
 type Consumer struct {
-	consumer *kafka.Consumer
+	consumer           *kafka.Consumer
+	deadLetters        *Producer
+	retryPolicy        RetryPolicy
+	deadLetterCallback func([]byte, error)
 }
 
 func NewConsumer(broker string, group string) (*Consumer, error) {
+	return newConsumer(broker, group, true, "earliest")
+}
+
+// NewManualCommitConsumer creates a consumer that never commits offsets on its
+// own, see ProcessMessagesEternally.
+func NewManualCommitConsumer(broker string, group string) (*Consumer, error) {
+	return newConsumer(broker, group, false, "earliest")
+}
+
+// NewBroadcastConsumer creates a consumer that gets every message of its
+// topics whatever the other replicas of the service do, each replica is a
+// group of its own. It starts from the latest messages, a replica is expected
+// to load its state on startup.
+func NewBroadcastConsumer(broker string, group string) (*Consumer, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	return newConsumer(broker, fmt.Sprintf("%s-%s", group, hostname), true, "latest")
+}
+
+func newConsumer(broker string, group string, autoCommit bool, offsetReset string) (*Consumer, error) {
 	c, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers": broker,
-		"group.id":          group,
-		"auto.offset.reset": "earliest",
+		"bootstrap.servers":        broker,
+		"group.id":                 group,
+		"auto.offset.reset":        offsetReset,
+		"enable.auto.commit":       autoCommit,
+		"allow.auto.create.topics": true,
 	})
-
 	if err != nil {
 		return nil, err
 	}
@@ -26,6 +59,19 @@ func NewConsumer(broker string, group string) (*Consumer, error) {
 	}, nil
 }
 
+// EnableDeadLettering makes the consumer retry failed messages according to
+// the policy and then move them to the dead-letter topic of their source topic.
+func (c *Consumer) EnableDeadLettering(producer *Producer, policy RetryPolicy) {
+	c.deadLetters = producer
+	c.retryPolicy = policy
+}
+
+// OnDeadLetter sets the callback which is called with the value of every
+// message moved to the dead-letter topic and the error that has moved it.
+func (c *Consumer) OnDeadLetter(callback func([]byte, error)) {
+	c.deadLetterCallback = callback
+}
+
 func (c *Consumer) SubscribeTopics(topics []string) error {
 	return c.consumer.SubscribeTopics(topics, nil)
 }
@@ -34,6 +80,11 @@ func (c *Consumer) Consume(timeout time.Duration) (*kafka.Message, error) {
 	return c.consumer.ReadMessage(timeout)
 }
 
+func (c *Consumer) Commit(msg *kafka.Message) error {
+	_, err := c.consumer.CommitMessage(msg)
+	return err
+}
+
 func (c *Consumer) Close() error {
 	return c.consumer.Close()
 }
@@ -43,33 +94,148 @@ func (c *Consumer) ConsumeMessagesEternally(dataCallback func([]byte) error, suc
 		return
 	}
 
-	safeCallback := func(callback func(string, ...interface{}), format string, v ...interface{}) {
-		if callback != nil {
-			callback(format, v...)
+	c.readEternally(func(msg *kafka.Message) {
+		err := c.handle(msg, dataCallback, errorCallback)
+
+		if err != nil {
+			safeCallback(errorCallback, "got an error while consuming messages: %s", err.Error())
+		} else {
+			safeCallback(successCallback, "message consumed successfully")
+		}
+	}, errorCallback)
+}
+
+// ProcessMessagesEternally handles messages one at a time, in partition order,
+// and commits the offset of a message only after processCallback has succeeded
+// for it. A failed message is read again instead of the ones after it, and a
+// crash in the middle of processing leads to redelivery instead of a lost
+// message, so processCallback has to be idempotent.
+func (c *Consumer) ProcessMessagesEternally(processCallback func([]byte) error, successCallback func(string, ...interface{}), errorCallback func(string, ...interface{})) {
+	if processCallback == nil {
+		return
+	}
+
+	c.readEternally(func(msg *kafka.Message) {
+		err := c.handle(msg, processCallback, errorCallback)
+		if err != nil {
+			safeCallback(errorCallback, "got an error while processing message: %s", err.Error())
+			c.rewind(msg, errorCallback)
+			return
+		}
+
+		err = c.Commit(msg)
+		if err != nil {
+			safeCallback(errorCallback, "unable to commit offset: %s", err.Error())
+			return
+		}
+		safeCallback(successCallback, "message processed successfully")
+	}, errorCallback)
+}
+
+// ConsumeDeadLettersEternally reads messages of dead-letter topics and commits
+// them once deadLetterCallback has stored them successfully. A letter that
+// can't be stored is read again before the ones after it.
+func (c *Consumer) ConsumeDeadLettersEternally(deadLetterCallback func(DeadLetter) error, successCallback func(string, ...interface{}), errorCallback func(string, ...interface{})) {
+	if deadLetterCallback == nil {
+		return
+	}
+
+	c.readEternally(func(msg *kafka.Message) {
+		err := deadLetterCallback(deadLetterFromMessage(msg))
+		if err != nil {
+			safeCallback(errorCallback, "unable to store dead letter: %s", err.Error())
+			c.rewind(msg, errorCallback)
+			return
+		}
+
+		err = c.Commit(msg)
+		if err != nil {
+			safeCallback(errorCallback, "unable to commit offset: %s", err.Error())
+			return
+		}
+		safeCallback(successCallback, "dead letter stored successfully")
+	}, errorCallback)
+}
+
+// rewind makes the consumer read the message again after a pause. The
+// messages after it in the partition aren't read until it has been handled.
+// A partition which has been revoked meanwhile is left to its new owner, that
+// starts from the last committed offset anyway.
+func (c *Consumer) rewind(msg *kafka.Message, errorCallback func(string, ...interface{})) {
+	for {
+		time.Sleep(rewindDelay)
+
+		err := c.consumer.Seek(msg.TopicPartition, 0)
+		if err == nil || !c.isAssigned(msg.TopicPartition) {
+			return
 		}
+		safeCallback(errorCallback, "unable to seek back to the failed message: %s", err.Error())
+	}
+}
+
+func (c *Consumer) isAssigned(partition kafka.TopicPartition) bool {
+	assignment, err := c.consumer.Assignment()
+	if err != nil {
+		return true
 	}
 
-	// TODO: redis
-	messageKeys := make(map[string]bool)
+	for _, assigned := range assignment {
+		if *assigned.Topic == *partition.Topic && assigned.Partition == partition.Partition {
+			return true
+		}
+	}
+	return false
+}
 
+func (c *Consumer) readEternally(onMessage func(*kafka.Message), errorCallback func(string, ...interface{})) {
 	for {
 		msg, err := c.Consume(time.Second)
-		if err == nil {
-			keyStr := string(msg.Key)
-			if _, ok := messageKeys[keyStr]; ok {
-				continue
+		if err != nil {
+			if !err.(kafka.Error).IsTimeout() {
+				go safeCallback(errorCallback, "consumer error: %s", err.Error())
 			}
-			messageKeys[keyStr] = true
+			continue
+		}
 
-			err := dataCallback(msg.Value)
+		onMessage(msg)
+	}
+}
 
-			if err != nil {
-				safeCallback(errorCallback, "got an error while consuming messages: %s", err.Error())
-			} else {
-				safeCallback(successCallback, "message consumed successfully")
-			}
-		} else if !err.(kafka.Error).IsTimeout() {
-			go safeCallback(errorCallback, "consumer error: %s", err.Error())
-		}
+// handle runs the callback with retries. When dead lettering is enabled an
+// exhausted message is moved to the dead-letter topic and counts as handled.
+func (c *Consumer) handle(msg *kafka.Message, callback func([]byte) error, errorCallback func(string, ...interface{})) error {
+	if c.deadLetters == nil {
+		return callback(msg.Value)
+	}
+
+	attempts := 1
+	err := callback(msg.Value)
+	for err != nil && !IsPermanent(err) && attempts <= c.retryPolicy.MaxRetries {
+		safeCallback(errorCallback, "attempt %d failed: %s", attempts, err.Error())
+		time.Sleep(c.retryPolicy.delay(attempts))
+
+		attempts++
+		err = callback(msg.Value)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	deadLetterErr := c.deadLetters.produceDeadLetter(msg, err, attempts)
+	if deadLetterErr != nil {
+		return fmt.Errorf("%w (unable to dead-letter the message: %s)", err, deadLetterErr.Error())
+	}
+
+	safeCallback(errorCallback, "message moved to %s after %d attempts: %s", DeadLetterTopic(*msg.TopicPartition.Topic), attempts, err.Error())
+	if c.deadLetterCallback != nil {
+		c.deadLetterCallback(msg.Value, err)
+	}
+	return nil
+}
+
+func safeCallback(callback func(string, ...interface{}), format string, v ...interface{}) {
+	if callback != nil {
+		callback(format, v...)
 	}
 }
@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+const (
+	deadLetterSuffix = ".dlq"
+
+	headerOriginalTopic     = "dlq-original-topic"
+	headerOriginalPartition = "dlq-original-partition"
+	headerOriginalOffset    = "dlq-original-offset"
+	headerError             = "dlq-error"
+	headerAttempts          = "dlq-attempts"
+	headerFailedAt          = "dlq-failed-at"
+)
+
+type RetryPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+// delay returns the exponential backoff before the given retry, starting at 1.
+func (r RetryPolicy) delay(retry int) time.Duration {
+	return r.Backoff * time.Duration(1<<(retry-1))
+}
+
+type DeadLetter struct {
+	Topic    string
+	Key      []byte
+	Value    []byte
+	Error    string
+	Attempts int
+	FailedAt time.Time
+}
+
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+// Permanent marks an error that retrying cannot fix, e.g. a malformed payload,
+// so the message goes to the dead-letter topic right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+func DeadLetterTopic(topic string) string {
+	return topic + deadLetterSuffix
+}
+
+// produceDeadLetter returns only once the broker has confirmed the delivery,
+// the source message is committed after that and would be lost otherwise.
+func (p *Producer) produceDeadLetter(msg *kafka.Message, reason error, attempts int) error {
+	topic := DeadLetterTopic(*msg.TopicPartition.Topic)
+	headers := append(msg.Headers,
+		kafka.Header{Key: headerOriginalTopic, Value: []byte(*msg.TopicPartition.Topic)},
+		kafka.Header{Key: headerOriginalPartition, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: headerOriginalOffset, Value: []byte(msg.TopicPartition.Offset.String())},
+		kafka.Header{Key: headerError, Value: []byte(reason.Error())},
+		kafka.Header{Key: headerAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: headerFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	return p.produceAndWait(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          msg.Value,
+		Key:            msg.Key,
+		Headers:        headers,
+	})
+}
+
+func deadLetterFromMessage(msg *kafka.Message) DeadLetter {
+	result := DeadLetter{
+		Key:   msg.Key,
+		Value: msg.Value,
+	}
+
+	for _, header := range msg.Headers {
+		value := string(header.Value)
+		switch header.Key {
+		case headerOriginalTopic:
+			result.Topic = value
+		case headerError:
+			result.Error = value
+		case headerAttempts:
+			result.Attempts, _ = strconv.Atoi(value)
+		case headerFailedAt:
+			result.FailedAt, _ = time.Parse(time.RFC3339, value)
+		}
+	}
+
+	if result.FailedAt.IsZero() {
+		result.FailedAt = msg.Timestamp
+	}
+
+	return result
+}
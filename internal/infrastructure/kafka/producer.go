@@ -1,6 +1,8 @@
 package kafka
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -8,7 +10,6 @@ import (
 
 type Producer struct {
 	producer *kafka.Producer
-	keyID    uint
 	ID       uint
 }
 
@@ -23,21 +24,57 @@ func NewProducer(broker string, id uint) (*Producer, error) {
 	}
 	return &Producer{
 		producer: p,
-		keyID:    0,
 		ID:       id,
 	}, nil
 }
 
 func (p *Producer) Produce(topic string, message []byte) error {
-	p.keyID++
+	return p.ProduceWithKey(topic, fmt.Sprintf("%d-%s", p.ID, NewMessageKey()), message)
+}
+
+func (p *Producer) ProduceWithKey(topic string, key string, message []byte) error {
 	return p.producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
 		Value:          message,
-		Key:            []byte(fmt.Sprintf("%d-%d", p.keyID, p.ID)),
+		Key:            []byte(key),
 	}, nil)
 }
 
+// ProduceWithKeyAndWait returns only once the broker has confirmed the
+// delivery, for the callers that mustn't consider a lost message sent.
+func (p *Producer) ProduceWithKeyAndWait(topic string, key string, message []byte) error {
+	return p.produceAndWait(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          message,
+		Key:            []byte(key),
+	})
+}
+
+func (p *Producer) produceAndWait(msg *kafka.Message) error {
+	delivery := make(chan kafka.Event, 1)
+	err := p.producer.Produce(msg, delivery)
+	if err != nil {
+		return err
+	}
+
+	switch event := (<-delivery).(type) {
+	case *kafka.Message:
+		return event.TopicPartition.Error
+	case kafka.Error:
+		return event
+	default:
+		return fmt.Errorf("unexpected delivery event: %s", event.String())
+	}
+}
+
 func (p *Producer) Close() {
 	p.producer.Flush(15 * 1000)
 	p.producer.Close()
 }
+
+// NewMessageKey returns a random key that stays unique across producer restarts.
+func NewMessageKey() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
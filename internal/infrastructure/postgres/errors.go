@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	UniqueViolation      = "23505"
+	ForeignKeyViolation  = "23503"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+
+	// raised by the order procedures in migrations/dal.sql
+	AlbumNotFound     = "AL001"
+	OrderNotFound     = "AL002"
+	AlreadyInOrder    = "AL003"
+	NotInOrder        = "AL004"
+	AlreadyOwned      = "AL005"
+	InsufficientFunds = "AL006"
+	OrderAlreadyPaid  = "AL007"
+	CurrencyMismatch  = "AL008"
+)
+
+func IsNoRows(err error) bool {
+	return errors.Is(err, pgx.ErrNoRows)
+}
+
+func ErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// IsSerializationFailure reports whether the transaction lost to a concurrent
+// one and may succeed if started over.
+func IsSerializationFailure(err error) bool {
+	code := ErrorCode(err)
+	return code == SerializationFailure || code == DeadlockDetected
+}
+
+// IsTransient reports whether the error is likely to go away on its own, like
+// a lost connection or a serialization conflict, as opposed to an error the
+// database raised because of the data. Errors that never reached the server
+// count as transient.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	code := ErrorCode(err)
+	switch {
+	case code == "":
+		return !IsNoRows(err)
+	case IsSerializationFailure(err):
+		return true
+	default:
+		// class 08 is "connection exception"
+		return strings.HasPrefix(code, "08")
+	}
+}
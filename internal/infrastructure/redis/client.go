@@ -15,8 +15,15 @@ var (
 
 type Client interface {
 	Set(ctx context.Context, key string, value interface{}, exp time.Duration) error
+	SetNX(ctx context.Context, key string, value interface{}, exp time.Duration) (bool, error)
 	Get(ctx context.Context, key string) (string, error)
+	GetDel(ctx context.Context, key string) (string, error)
 	Del(ctx context.Context, keys ...string) error
+	SAdd(ctx context.Context, key string, members ...interface{}) error
+	SRem(ctx context.Context, key string, members ...interface{}) error
+	SMembers(ctx context.Context, key string) ([]string, error)
+	Publish(ctx context.Context, channel string, message interface{}) error
+	NewPubSub(ctx context.Context) PubSub
 	Close() error
 	Ping(ctx context.Context) error
 }
@@ -48,6 +55,19 @@ func (c *client) Set(ctx context.Context, key string, value interface{}, exp tim
 	return nil
 }
 
+func (c *client) SetNX(ctx context.Context, key string, value interface{}, exp time.Duration) (bool, error) {
+	err := c.Ping(ctx)
+	if err != nil {
+		return false, ErrRedis
+	}
+
+	ok, err := c.cl.SetNX(ctx, key, value, exp).Result()
+	if err != nil {
+		return false, ErrRedis
+	}
+	return ok, nil
+}
+
 func (c *client) Get(ctx context.Context, key string) (string, error) {
 	err := c.Ping(ctx)
 	if err != nil {
@@ -66,6 +86,24 @@ func (c *client) Get(ctx context.Context, key string) (string, error) {
 	return res, nil
 }
 
+func (c *client) GetDel(ctx context.Context, key string) (string, error) {
+	err := c.Ping(ctx)
+	if err != nil {
+		return "", ErrRedis
+	}
+
+	res, err := c.cl.GetDel(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrNotFound
+		} else {
+			return "", ErrRedis
+		}
+	}
+
+	return res, nil
+}
+
 func (c *client) Del(ctx context.Context, keys ...string) error {
 	err := c.Ping(ctx)
 	if err != nil {
@@ -83,6 +121,59 @@ func (c *client) Del(ctx context.Context, keys ...string) error {
 	return nil
 }
 
+func (c *client) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	err := c.Ping(ctx)
+	if err != nil {
+		return ErrRedis
+	}
+
+	err = c.cl.SAdd(ctx, key, members...).Err()
+	if err != nil {
+		return ErrRedis
+	}
+	return nil
+}
+
+func (c *client) SRem(ctx context.Context, key string, members ...interface{}) error {
+	err := c.Ping(ctx)
+	if err != nil {
+		return ErrRedis
+	}
+
+	err = c.cl.SRem(ctx, key, members...).Err()
+	if err != nil {
+		return ErrRedis
+	}
+	return nil
+}
+
+func (c *client) SMembers(ctx context.Context, key string) ([]string, error) {
+	err := c.Ping(ctx)
+	if err != nil {
+		return nil, ErrRedis
+	}
+
+	res, err := c.cl.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, ErrRedis
+	}
+	return res, nil
+}
+
+func (c *client) Publish(ctx context.Context, channel string, message interface{}) error {
+	err := c.cl.Publish(ctx, channel, message).Err()
+	if err != nil {
+		return ErrRedis
+	}
+	return nil
+}
+
+// NewPubSub creates a subscription without channels, they are added and
+// removed later as needed
+func (c *client) NewPubSub(ctx context.Context) PubSub {
+	return newPubSub(c.cl.Subscribe(ctx))
+}
+
 func (c *client) Close() error {
 	err := c.cl.Close()
 	if err != nil {
@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type Message struct {
+	Channel string
+	Payload string
+}
+
+// PubSub is a subscription to a changing set of channels. It resubscribes on
+// its own after a lost connection, the messages published meanwhile are lost.
+type PubSub interface {
+	Subscribe(ctx context.Context, channels ...string) error
+	Unsubscribe(ctx context.Context, channels ...string) error
+	Messages() <-chan Message
+	Close() error
+}
+
+type pubSub struct {
+	ps       *redis.PubSub
+	messages chan Message
+}
+
+func newPubSub(ps *redis.PubSub) PubSub {
+	result := &pubSub{
+		ps:       ps,
+		messages: make(chan Message),
+	}
+
+	go func() {
+		defer close(result.messages)
+		for msg := range ps.Channel() {
+			result.messages <- Message{
+				Channel: msg.Channel,
+				Payload: msg.Payload,
+			}
+		}
+	}()
+
+	return result
+}
+
+func (p *pubSub) Subscribe(ctx context.Context, channels ...string) error {
+	err := p.ps.Subscribe(ctx, channels...)
+	if err != nil {
+		return ErrRedis
+	}
+	return nil
+}
+
+func (p *pubSub) Unsubscribe(ctx context.Context, channels ...string) error {
+	err := p.ps.Unsubscribe(ctx, channels...)
+	if err != nil {
+		return ErrRedis
+	}
+	return nil
+}
+
+func (p *pubSub) Messages() <-chan Message {
+	return p.messages
+}
+
+func (p *pubSub) Close() error {
+	err := p.ps.Close()
+	if err != nil {
+		return ErrRedis
+	}
+	return nil
+}
@@ -3,12 +3,12 @@ package utils
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"reflect"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/allnightmarel0Ng/albums/internal/domain/api"
@@ -34,11 +34,17 @@ func Send(c *gin.Context, response api.Response) {
 }
 
 func Request(ctx context.Context, method, url, auth string, body io.Reader) (*http.Response, error) {
+	return RequestWithHeaders(ctx, method, url, map[string]string{"Authorization": auth}, body)
+}
+
+func RequestWithHeaders(ctx context.Context, method, url string, headers map[string]string, body io.Reader) (*http.Response, error) {
 	request, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
-	request.Header.Set("Authorization", auth)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
 
 	client := &http.Client{}
 	response, err := client.Do(request)
@@ -46,10 +52,14 @@ func Request(ctx context.Context, method, url, auth string, body io.Reader) (*ht
 }
 
 func RequestAndParseResponse(method, url, auth string, body io.Reader) (int, []byte) {
+	return RequestWithHeadersAndParseResponse(method, url, map[string]string{"Authorization": auth}, body)
+}
+
+func RequestWithHeadersAndParseResponse(method, url string, headers map[string]string, body io.Reader) (int, []byte) {
 	ctx, cancel := DeadlineContext(10)
 	defer cancel()
 
-	response, err := Request(ctx, method, url, auth, body)
+	response, err := RequestWithHeaders(ctx, method, url, headers, body)
 	if err != nil {
 		return InterserviceCommunicationErrorRaw()
 	}
@@ -85,6 +95,14 @@ func GetJWTClaims(jsonWebToken string, secretKey string) api.Response {
 	})
 
 	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return &api.AuthorizationResponse{
+				Code:  http.StatusUnauthorized,
+				Error: "token is expired",
+			}
+		}
+
 		return &api.AuthorizationResponse{
 			Code:  http.StatusInternalServerError,
 			Error: err.Error(),
@@ -98,6 +116,14 @@ func GetJWTClaims(jsonWebToken string, secretKey string) api.Response {
 		}
 	}
 
+	now := time.Now().Unix()
+	if !data.VerifyExpiresAt(now, true) || !data.VerifyIssuedAt(now, true) {
+		return &api.AuthorizationResponse{
+			Code:  http.StatusUnauthorized,
+			Error: "invalid token: missing or invalid 'exp'/'iat' claims",
+		}
+	}
+
 	var result api.AuthorizationResponse
 	idFloat, err := SafelyCastJWTClaim[float64](data, "id")
 	if err != nil {
@@ -150,14 +176,6 @@ func GetParam(c *gin.Context, name string) (int, error) {
 	return result, err
 }
 
-func SearchLikeString(str string) string {
-	result := strings.Replace(str, " ", "%", -1)
-	result = "%" + result
-	result += "%"
-	result = strings.ToLower(result)
-	return result
-}
-
 func Authorize(authHeader string, port string) api.Response {
 	ctx, cancel := DeadlineContext(10)
 	defer cancel()
@@ -190,6 +208,13 @@ func Authorize(authHeader string, port string) api.Response {
 }
 
 func ProduceNotificationMessage(message api.NotificationKafkaMessage, producer *kafka.Producer) error {
+	if message.Key == "" {
+		message.Key = kafka.NewMessageKey()
+	}
+	if message.Time.IsZero() {
+		message.Time = time.Now()
+	}
+
 	raw, err := json.Marshal(message)
 	if err != nil {
 		return err
@@ -197,3 +222,12 @@ func ProduceNotificationMessage(message api.NotificationKafkaMessage, producer *
 
 	return producer.Produce("notifications", raw)
 }
+
+func ProduceCatalogMessage(message api.CatalogKafkaMessage, producer *kafka.Producer) error {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return producer.Produce("catalog", raw)
+}
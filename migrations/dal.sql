@@ -1,26 +1,147 @@
+DROP FUNCTION IF EXISTS apply_ledger_entry(INT, VARCHAR, DECIMAL, INT, VARCHAR);
+
+CREATE OR REPLACE FUNCTION apply_ledger_entry(p_user_id INT, p_type VARCHAR, p_amount BIGINT, p_currency CHAR(3), p_order_id INT, p_operation_key VARCHAR)
+RETURNS BIGINT AS $$
+DECLARE
+    d_balance BIGINT;
+BEGIN
+    UPDATE public.users
+    SET balance = balance + p_amount
+    WHERE id = p_user_id AND currency = p_currency
+    RETURNING balance INTO d_balance;
+
+    IF d_balance IS NULL THEN
+        RAISE EXCEPTION 'user % with % balance not found', p_user_id, p_currency;
+    END IF;
+
+    INSERT INTO public.ledger_entries (user_id, type, amount, balance_after, currency, order_id, operation_key)
+    VALUES (p_user_id, p_type, p_amount, d_balance, p_currency, p_order_id, p_operation_key);
+
+    RETURN d_balance;
+END;
+$$ LANGUAGE PLPGSQL;
+
+CREATE OR REPLACE FUNCTION exchange_rate(p_from CHAR(3), p_to CHAR(3))
+RETURNS NUMERIC AS $$
+DECLARE
+    d_from_rate NUMERIC;
+    d_to_rate NUMERIC;
+BEGIN
+    SELECT rate INTO d_from_rate
+    FROM public.exchange_rates
+    WHERE currency = p_from;
+
+    SELECT rate INTO d_to_rate
+    FROM public.exchange_rates
+    WHERE currency = p_to;
+
+    IF d_from_rate IS NULL OR d_to_rate IS NULL THEN
+        RAISE EXCEPTION 'no exchange rate from % to %', p_from, p_to;
+    END IF;
+
+    RETURN d_to_rate / d_from_rate;
+END;
+$$ LANGUAGE PLPGSQL;
+
+-- convert_amount multiplies before dividing, so the exact amount is rounded
+-- half away from zero rather than the one at the rounded exchange_rate: 3
+-- cents at rates 1.2 and 1 are 2.5, but 3 * 0.8333... is 2.4999...
+CREATE OR REPLACE FUNCTION convert_amount(p_amount BIGINT, p_from CHAR(3), p_to CHAR(3))
+RETURNS BIGINT AS $$
+DECLARE
+    d_from_rate NUMERIC;
+    d_to_rate NUMERIC;
+BEGIN
+    SELECT rate INTO d_from_rate
+    FROM public.exchange_rates
+    WHERE currency = p_from;
+
+    SELECT rate INTO d_to_rate
+    FROM public.exchange_rates
+    WHERE currency = p_to;
+
+    IF d_from_rate IS NULL OR d_to_rate IS NULL THEN
+        RAISE EXCEPTION 'no exchange rate from % to %', p_from, p_to;
+    END IF;
+
+    RETURN ROUND(p_amount * d_to_rate / d_from_rate)::BIGINT;
+END;
+$$ LANGUAGE PLPGSQL;
+
+CREATE OR REPLACE PROCEDURE change_user_currency(p_user_id INT, p_currency CHAR(3))
+AS $$
+DECLARE
+    d_balance BIGINT;
+    d_currency CHAR(3);
+BEGIN
+    SELECT balance, currency INTO d_balance, d_currency
+    FROM public.users
+    WHERE id = p_user_id
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'user % not found', p_user_id;
+    END IF;
+
+    IF d_currency = p_currency THEN
+        RETURN;
+    END IF;
+
+    PERFORM apply_ledger_entry(p_user_id, 'adjustment', -d_balance, d_currency, NULL, NULL);
+
+    UPDATE public.users
+    SET currency = p_currency
+    WHERE id = p_user_id;
+
+    PERFORM apply_ledger_entry(p_user_id, 'adjustment', convert_amount(d_balance, d_currency, p_currency), p_currency, NULL, NULL);
+END;
+$$ LANGUAGE PLPGSQL;
+
+-- The order procedures raise errors of the AL class, which PostgreSQL doesn't use:
+--   AL001 album not found            AL005 album is already owned
+--   AL002 order not found            AL006 insufficient funds
+--   AL003 album is already in order  AL007 order is already paid
+--   AL004 album not in order         AL008 currency mismatch
 CREATE OR REPLACE PROCEDURE add_album_to_user_order(p_user_id INT, p_album_id INT)
 AS $$
 DECLARE
     d_order_id INT;
+    d_order_currency CHAR(3);
     d_order_item_id INT;
-    d_album_price DECIMAL(10, 2);
+    d_album_price BIGINT;
+    d_album_currency CHAR(3);
 BEGIN
-    SELECT price INTO d_album_price 
+    SELECT price, currency INTO d_album_price, d_album_currency
     FROM public.albums 
     WHERE id = p_album_id;
 
     IF d_album_price IS NULL THEN
-        ROLLBACK;
+        RAISE EXCEPTION 'album % not found', p_album_id
+            USING ERRCODE = 'AL001';
+    END IF;
+
+    IF EXISTS (
+        SELECT 1
+        FROM public.purchased_albums
+        WHERE user_id = p_user_id AND album_id = p_album_id
+    ) THEN
+        RAISE EXCEPTION 'album % is already owned by user %', p_album_id, p_user_id
+            USING ERRCODE = 'AL005';
     END IF;
 
-    SELECT id INTO d_order_id
+    SELECT id, currency INTO d_order_id, d_order_currency
     FROM public.orders 
     WHERE user_id = p_user_id AND is_paid = FALSE;
 
     IF d_order_id IS NULL THEN
-        INSERT INTO public.orders (user_id) 
-        VALUES (p_user_id)
-        RETURNING id INTO d_order_id;
+        INSERT INTO public.orders (user_id, currency)
+        VALUES (p_user_id, d_album_currency)
+        RETURNING id, currency INTO d_order_id, d_order_currency;
+    END IF;
+
+    IF d_order_currency <> d_album_currency THEN
+        RAISE EXCEPTION 'album % is priced in %, order % is in %', p_album_id, d_album_currency, d_order_id, d_order_currency
+            USING ERRCODE = 'AL008';
     END IF;
 
     SELECT id INTO d_order_item_id 
@@ -28,7 +149,8 @@ BEGIN
     WHERE order_id = d_order_id AND album_id = p_album_id;
     
     IF d_order_item_id IS NOT NULL THEN
-        ROLLBACK;
+        RAISE EXCEPTION 'album % is already in order %', p_album_id, d_order_id
+            USING ERRCODE = 'AL003';
     END IF;
 
     INSERT INTO public.order_items (order_id, album_id)
@@ -40,19 +162,50 @@ BEGIN
 END;
 $$ LANGUAGE PLPGSQL;
 
+CREATE OR REPLACE PROCEDURE move_wishlist_to_order(p_user_id INT)
+AS $$
+DECLARE
+    d_album_id INT;
+BEGIN
+    FOR d_album_id IN
+        SELECT w.album_id
+        FROM public.wishlist_items AS w
+        WHERE w.user_id = p_user_id
+            AND NOT EXISTS (
+                SELECT 1
+                FROM public.purchased_albums AS pa
+                WHERE pa.user_id = p_user_id AND pa.album_id = w.album_id
+            )
+            AND NOT EXISTS (
+                SELECT 1
+                FROM public.order_items AS oi
+                JOIN public.orders AS o ON oi.order_id = o.id
+                WHERE o.user_id = p_user_id AND o.is_paid = FALSE AND oi.album_id = w.album_id
+            )
+        ORDER BY w.added_at
+    LOOP
+        CALL add_album_to_user_order(p_user_id, d_album_id);
+    END LOOP;
+
+    DELETE FROM public.wishlist_items
+    WHERE user_id = p_user_id;
+END;
+$$ LANGUAGE PLPGSQL;
+
 CREATE OR REPLACE PROCEDURE delete_album_from_user_order(p_user_id INT, p_album_id INT)
 AS $$
 DECLARE
     d_order_id INT;
     d_order_item_id INT;
-    d_album_price DECIMAL(10, 2);
+    d_album_price BIGINT;
 BEGIN
     SELECT price INTO d_album_price 
     FROM public.albums 
     WHERE id = p_album_id;
 
     IF d_album_price IS NULL THEN
-        ROLLBACK;
+        RAISE EXCEPTION 'album % not found', p_album_id
+            USING ERRCODE = 'AL001';
     END IF;
 
     SELECT id INTO d_order_id 
@@ -60,7 +213,8 @@ BEGIN
     WHERE user_id = p_user_id AND is_paid = FALSE;
 
     IF d_order_id IS NULL THEN
-        ROLLBACK;
+        RAISE EXCEPTION 'user % has no unpaid order', p_user_id
+            USING ERRCODE = 'AL002';
     END IF;
 
     SELECT id INTO d_order_item_id
@@ -68,7 +222,8 @@ BEGIN
     WHERE order_id = d_order_id AND album_id = p_album_id;
 
     IF d_order_item_id IS NULL THEN
-        ROLLBACK;
+        RAISE EXCEPTION 'album % is not in order %', p_album_id, d_order_id
+            USING ERRCODE = 'AL004';
     END IF;
 
     DELETE 
@@ -81,36 +236,108 @@ BEGIN
 END;
 $$ LANGUAGE PLPGSQL;
 
-CREATE OR REPLACE PROCEDURE pay_for_order(p_user_id INT, p_order_id INT)
+DROP PROCEDURE IF EXISTS pay_for_order(INT, INT);
+
+CREATE OR REPLACE PROCEDURE pay_for_order(p_user_id INT, p_order_id INT, p_operation_key VARCHAR)
 AS $$
 DECLARE
-    d_total_price DECIMAL(10, 2);
-    d_user_balance INT;
+    d_total_price BIGINT;
+    d_order_currency CHAR(3);
+    d_user_balance BIGINT;
+    d_user_currency CHAR(3);
     d_is_paid BOOLEAN;
+    d_rate NUMERIC;
+    d_paid_amount BIGINT;
 BEGIN
-    SELECT total_price, is_paid INTO d_total_price, d_is_paid
+    SELECT total_price, currency, is_paid INTO d_total_price, d_order_currency, d_is_paid
     FROM public.orders
-    WHERE id = p_order_id;
+    WHERE id = p_order_id AND user_id = p_user_id
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'order % not found', p_order_id
+            USING ERRCODE = 'AL002';
+    END IF;
 
-    IF d_total_price IS NULL OR d_is_paid = TRUE THEN
-        ROLLBACK;
+    IF d_is_paid = TRUE THEN
+        RAISE EXCEPTION 'order % has already been paid', p_order_id
+            USING ERRCODE = 'AL007';
     END IF;
 
-    SELECT balance INTO d_user_balance
+    SELECT balance, currency INTO d_user_balance, d_user_currency
     FROM public.users
     WHERE id = p_user_id;
 
-    IF d_user_balance IS NULL OR d_user_balance < d_total_price THEN
-        ROLLBACK;
+    d_rate := exchange_rate(d_order_currency, d_user_currency);
+    d_paid_amount := convert_amount(d_total_price, d_order_currency, d_user_currency);
+
+    IF d_user_balance IS NULL OR d_user_balance < d_paid_amount THEN
+        RAISE EXCEPTION 'user % can not afford % %', p_user_id, d_paid_amount, d_user_currency
+            USING ERRCODE = 'AL006';
+    END IF;
+
+    -- the albums could have been bought in another order after they were added
+    IF EXISTS (
+        SELECT 1
+        FROM public.order_items AS oi
+        JOIN public.purchased_albums AS pa ON pa.album_id = oi.album_id AND pa.user_id = p_user_id
+        WHERE oi.order_id = p_order_id
+    ) THEN
+        RAISE EXCEPTION 'order % contains albums already owned by user %', p_order_id, p_user_id
+            USING ERRCODE = 'AL005';
     END IF;
 
     UPDATE public.orders
-    SET is_paid = TRUE
+    SET is_paid = TRUE,
+        paid_amount = d_paid_amount,
+        paid_currency = d_user_currency,
+        exchange_rate = d_rate
     WHERE id = p_order_id;
 
-    UPDATE public.users
-    SET balance = balance - d_total_price
+    PERFORM apply_ledger_entry(p_user_id, 'purchase', -d_paid_amount, d_user_currency, p_order_id, p_operation_key);
+END;
+$$ LANGUAGE PLPGSQL;
+
+CREATE OR REPLACE PROCEDURE refund_order(p_user_id INT, p_order_id INT, p_operation_key VARCHAR)
+AS $$
+DECLARE
+    d_amount BIGINT;
+    d_currency CHAR(3);
+    d_user_currency CHAR(3);
+    d_is_paid BOOLEAN;
+    d_is_refunded BOOLEAN;
+BEGIN
+    SELECT COALESCE(paid_amount, total_price), COALESCE(paid_currency, currency), is_paid, is_refunded INTO d_amount, d_currency, d_is_paid, d_is_refunded
+    FROM public.orders
+    WHERE id = p_order_id AND user_id = p_user_id
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'order % not found', p_order_id;
+    END IF;
+
+    IF d_is_paid = FALSE OR d_is_refunded = TRUE THEN
+        RAISE EXCEPTION 'order % cannot be refunded', p_order_id;
+    END IF;
+
+    UPDATE public.orders
+    SET is_refunded = TRUE
+    WHERE id = p_order_id;
+
+    DELETE
+    FROM public.purchased_albums AS pa
+    USING public.order_items AS oi
+    WHERE oi.order_id = p_order_id AND pa.album_id = oi.album_id AND pa.user_id = p_user_id;
+
+    SELECT currency INTO d_user_currency
+    FROM public.users
     WHERE id = p_user_id;
+
+    IF d_user_currency <> d_currency THEN
+        d_amount := convert_amount(d_amount, d_currency, d_user_currency);
+    END IF;
+
+    PERFORM apply_ledger_entry(p_user_id, 'refund', d_amount, d_user_currency, p_order_id, p_operation_key);
 END;
 $$ LANGUAGE PLPGSQL;
 
@@ -118,8 +345,8 @@ CREATE OR REPLACE FUNCTION log_paid_order()
 RETURNS TRIGGER AS $$
 BEGIN
     IF NEW.is_paid = TRUE AND OLD.is_paid = FALSE THEN
-        INSERT INTO public.buy_logs (buyer_id, album_id)
-        SELECT NEW.user_id, oi.album_id
+        INSERT INTO public.buy_logs (buyer_id, album_id, order_id)
+        SELECT NEW.user_id, oi.album_id, NEW.id
         FROM public.order_items AS oi
         WHERE oi.order_id = NEW.id;
         INSERT INTO public.purchased_albums (user_id, album_id)
@@ -1,20 +1,39 @@
+DROP MATERIALIZED VIEW IF EXISTS public.charts;
+DROP MATERIALIZED VIEW IF EXISTS public.album_co_purchases;
+DROP TABLE IF EXISTS public.ledger_entries CASCADE;
+DROP TABLE IF EXISTS public.dead_letters CASCADE;
+DROP TABLE IF EXISTS public.processed_operations CASCADE;
 DROP TABLE IF EXISTS public.notifications CASCADE;
 DROP TABLE IF EXISTS public.buy_logs CASCADE;
 DROP TABLE IF EXISTS public.order_items CASCADE;
 DROP TABLE IF EXISTS public.orders CASCADE;
+DROP TABLE IF EXISTS public.wishlist_items CASCADE;
 DROP TABLE IF EXISTS public.purchased_albums CASCADE;
 DROP TABLE IF EXISTS public.tracks CASCADE;
 DROP TABLE IF EXISTS public.albums CASCADE;
 DROP TABLE IF EXISTS public.artists CASCADE;
 DROP TABLE IF EXISTS public.credentials CASCADE;
 DROP TABLE IF EXISTS public.users CASCADE;
+DROP TABLE IF EXISTS public.exchange_rates CASCADE;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE public.exchange_rates (
+    currency CHAR(3) PRIMARY KEY,
+    rate NUMERIC NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (currency <> 'USD' OR rate = 1)
+);
+
+INSERT INTO public.exchange_rates (currency, rate) VALUES ('USD', 1);
 
 CREATE TABLE public.users (
     id SERIAL PRIMARY KEY,
     email VARCHAR(100) NOT NULL UNIQUE,
     is_admin BOOLEAN NOT NULL DEFAULT FALSE,
     nickname VARCHAR(30) NOT NULL,
-    balance DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    balance BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'USD' REFERENCES public.exchange_rates(currency),
     image_url VARCHAR(255) NOT NULL DEFAULT '-'
 );
 
@@ -28,36 +47,78 @@ CREATE TABLE public.artists (
     id SERIAL PRIMARY KEY,
     name VARCHAR(512) NOT NULL,
     genre VARCHAR(64) NOT NULL,
-    image_url VARCHAR(128) NOT NULL
+    image_url VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    random_key DOUBLE PRECISION NOT NULL DEFAULT RANDOM(),
+    name_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED,
+    genre_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', genre)) STORED
 );
 
+CREATE INDEX artists_name_vector_idx ON public.artists USING GIN (name_vector);
+CREATE INDEX artists_genre_vector_idx ON public.artists USING GIN (genre_vector);
+CREATE INDEX artists_name_trgm_idx ON public.artists USING GIN (name gin_trgm_ops);
+CREATE INDEX artists_genre_trgm_idx ON public.artists USING GIN (genre gin_trgm_ops);
+CREATE INDEX artists_random_key_idx ON public.artists (random_key);
+
 CREATE TABLE public.albums (
     id SERIAL PRIMARY KEY,
     name VARCHAR(512) NOT NULL,
     artist_id INT REFERENCES public.artists(id) ON DELETE SET NULL,
     image_url VARCHAR(128) NOT NULL,
-    price DECIMAL(10, 2) NOT NULL
+    price BIGINT NOT NULL CHECK (price >= 0),
+    currency CHAR(3) NOT NULL DEFAULT 'USD' CHECK (currency = 'USD'),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    random_key DOUBLE PRECISION NOT NULL DEFAULT RANDOM(),
+    name_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED
 );
 
+CREATE INDEX albums_name_vector_idx ON public.albums USING GIN (name_vector);
+CREATE INDEX albums_name_trgm_idx ON public.albums USING GIN (name gin_trgm_ops);
+CREATE INDEX albums_artist_id_idx ON public.albums (artist_id);
+CREATE INDEX albums_price_idx ON public.albums (price);
+CREATE INDEX albums_created_at_idx ON public.albums (created_at DESC);
+CREATE INDEX albums_random_key_idx ON public.albums (random_key);
+
 CREATE TABLE public.tracks (
     id SERIAL PRIMARY KEY,
     album_id INT REFERENCES public.albums(id) ON DELETE SET NULL,
     name VARCHAR(512) NOT NULL,
-    number INT NOT NULL
+    number INT NOT NULL,
+    name_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED,
+    UNIQUE (album_id, number)
 );
 
+CREATE INDEX tracks_name_vector_idx ON public.tracks USING GIN (name_vector);
+CREATE INDEX tracks_name_trgm_idx ON public.tracks USING GIN (name gin_trgm_ops);
+
 CREATE TABLE public.purchased_albums (
     id SERIAL PRIMARY KEY,
     user_id INT REFERENCES public.users(id) ON DELETE CASCADE,
-    album_id INT REFERENCES public.albums(id) ON DELETE CASCADE 
+    album_id INT REFERENCES public.albums(id) ON DELETE CASCADE,
+    UNIQUE (user_id, album_id)
+);
+
+CREATE INDEX purchased_albums_album_id_idx ON public.purchased_albums (album_id);
+
+CREATE TABLE public.wishlist_items (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    album_id INT NOT NULL REFERENCES public.albums(id) ON DELETE CASCADE,
+    added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, album_id)
 );
 
 CREATE TABLE public.orders (
     id SERIAL PRIMARY KEY,
     user_id INT REFERENCES public.users(id) ON DELETE SET NULL,
     date TIMESTAMP NOT NULL DEFAULT NOW(),
-    total_price DECIMAL(10, 2) NOT NULL DEFAULT 0,
-    is_paid BOOLEAN NOT NULL DEFAULT FALSE
+    total_price BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    is_paid BOOLEAN NOT NULL DEFAULT FALSE,
+    is_refunded BOOLEAN NOT NULL DEFAULT FALSE,
+    paid_amount BIGINT,
+    paid_currency CHAR(3),
+    exchange_rate NUMERIC
 );
 
 CREATE TABLE public.order_items (
@@ -70,5 +131,125 @@ CREATE TABLE public.buy_logs (
     id SERIAL PRIMARY KEY,
     buyer_id INT REFERENCES public.users(id) ON DELETE SET NULL,
     album_id INT REFERENCES public.albums(id) ON DELETE SET NULL,
+    order_id INT REFERENCES public.orders(id) ON DELETE SET NULL,
     logging_time TIMESTAMP NOT NULL DEFAULT NOW()
 );
+
+CREATE INDEX buy_logs_album_id_idx ON public.buy_logs (album_id, logging_time);
+
+CREATE TABLE public.processed_operations (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES public.users(id) ON DELETE CASCADE,
+    operation_key VARCHAR(128) NOT NULL,
+    processed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, operation_key)
+);
+
+CREATE TABLE public.dead_letters (
+    id SERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    message_key VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    error TEXT NOT NULL,
+    attempts INT NOT NULL,
+    failed_at TIMESTAMP NOT NULL,
+    republished_at TIMESTAMP
+);
+
+CREATE TABLE public.ledger_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES public.users(id) ON DELETE CASCADE,
+    type VARCHAR(16) NOT NULL CHECK (type IN ('deposit', 'purchase', 'refund', 'adjustment')),
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    order_id INT REFERENCES public.orders(id) ON DELETE SET NULL,
+    operation_key VARCHAR(128),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ledger_entries_user_id_idx ON public.ledger_entries (user_id, created_at DESC);
+
+CREATE TABLE public.notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    -- the key of the Kafka message, a redelivered message is saved once
+    message_key VARCHAR(64) UNIQUE,
+    type VARCHAR(16) NOT NULL CHECK (type IN ('deposit', 'buy', 'refund', 'album_deleted')),
+    status VARCHAR(16) NOT NULL CHECK (status IN ('succeeded', 'failed', 'info')),
+    order_id INT,
+    -- no reference, the album may be deleted, which is what the notification is about
+    album_id INT,
+    album_name VARCHAR(512),
+    amount BIGINT,
+    currency CHAR(3),
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX notifications_user_id_idx ON public.notifications (user_id, id DESC);
+CREATE INDEX notifications_unread_idx ON public.notifications (user_id, id) WHERE is_read = FALSE;
+
+-- album_co_purchases counts the users who own both albums, recommendations
+-- read it instead of joining the purchases on every request. The search
+-- engine refreshes it periodically, the unique index lets it do that
+-- concurrently.
+CREATE MATERIALIZED VIEW public.album_co_purchases AS
+SELECT pa.album_id, other.album_id AS other_album_id, COUNT(*)::INT AS buyers
+FROM public.purchased_albums AS pa
+JOIN public.purchased_albums AS other ON other.user_id = pa.user_id AND other.album_id <> pa.album_id
+GROUP BY pa.album_id, other.album_id;
+
+CREATE UNIQUE INDEX album_co_purchases_idx ON public.album_co_purchases (album_id, other_album_id);
+
+-- charts rank albums and artists by purchases over the last day, week and
+-- month, overall and within their genre. Purchases of refunded orders don't
+-- count. The search engine refreshes the view periodically, the unique index
+-- lets it do that concurrently.
+CREATE MATERIALIZED VIEW public.charts AS
+WITH periods (period, since) AS (
+    VALUES
+        ('day', NOW() - INTERVAL '1 day'),
+        ('week', NOW() - INTERVAL '7 days'),
+        ('month', NOW() - INTERVAL '30 days')
+), album_sales AS (
+    SELECT p.period, a.id AS album_id, a.artist_id, LOWER(ar.genre) AS genre, COUNT(*)::INT AS purchases
+    FROM public.buy_logs AS bl
+    JOIN periods AS p ON bl.logging_time > p.since
+    JOIN public.albums AS a ON a.id = bl.album_id
+    JOIN public.artists AS ar ON ar.id = a.artist_id
+    WHERE NOT EXISTS (
+        SELECT 1
+        FROM public.orders AS o
+        WHERE o.id = bl.order_id AND o.is_refunded = TRUE
+    )
+    GROUP BY p.period, a.id, a.artist_id, LOWER(ar.genre)
+), artist_sales AS (
+    SELECT period, artist_id, genre, SUM(purchases)::INT AS purchases
+    FROM album_sales
+    GROUP BY period, artist_id, genre
+)
+SELECT
+    period,
+    'album' AS entity,
+    album_id AS id,
+    genre,
+    purchases,
+    RANK() OVER (PARTITION BY period ORDER BY purchases DESC)::INT AS position,
+    RANK() OVER (PARTITION BY period, genre ORDER BY purchases DESC)::INT AS genre_position,
+    NOW() AS computed_at
+FROM album_sales
+UNION ALL
+SELECT
+    period,
+    'artist',
+    artist_id,
+    genre,
+    purchases,
+    RANK() OVER (PARTITION BY period ORDER BY purchases DESC)::INT,
+    RANK() OVER (PARTITION BY period, genre ORDER BY purchases DESC)::INT,
+    NOW()
+FROM artist_sales;
+
+CREATE UNIQUE INDEX charts_idx ON public.charts (period, entity, id);
+CREATE INDEX charts_position_idx ON public.charts (period, entity, position);
@@ -21,6 +21,7 @@ SEARCH_URL = "http://localhost:{GATEWAY_PORT}/search"
 ADD_TO_ORDER_URL = "http://localhost:{GATEWAY_PORT}/add/{album_id}"
 REMOVE_FROM_ORDER_URL = "http://localhost:{GATEWAY_PORT}/remove/{album_id}"
 ORDERS_URL = "http://localhost:{GATEWAY_PORT}/orders/"
+OPERATION_URL = "http://localhost:{GATEWAY_PORT}/operations/{id}?wait=5s"
 DELETE_ALBUM = "http://localhost:{GATEWAY_PORT}/admin-panel/delete/{id}"
 
 ADMIN_PASS = os.getenv("ADMIN_PASS", "")
@@ -128,12 +129,23 @@ def fetch_data():
         st.error(f"Error fetching data: {e}")
         return None
 
-def search(query):
+def fetch_chart(period="week", entity="album", limit=10):
+    url = f"http://localhost:{GATEWAY_PORT}/charts"
+    try:
+        response = requests.get(url, params={"period": period, "entity": entity, "limit": limit})
+        response.raise_for_status()
+        return response.json().get("entries", [])
+    except requests.exceptions.RequestException:
+        return []
+
+def search(query, sort="relevance", not_purchased=False):
     url = SEARCH_URL.format(GATEWAY_PORT=GATEWAY_PORT)
-    payload = {"query": query}
+    params = {"q": query, "sort": sort}
+    if not_purchased:
+        params["notPurchased"] = "true"
     headers = get_authorization_header()
     try:
-        response = requests.post(url, json=payload, headers=headers)
+        response = requests.get(url, params=params, headers=headers)
         response.raise_for_status()
         return response.json()
     except requests.exceptions.RequestException as e:
@@ -155,6 +167,19 @@ def chunked(iterable, size):
     it = iter(iterable)
     return iter(lambda: list(islice(it, size)), [])
 
+def format_money(money):
+    amount = money["amount"]
+    sign = "-" if amount < 0 else ""
+    return f"{sign}{abs(amount) // 100}.{abs(amount) % 100:02d} {money['currency']}"
+
+def wait_for_operation(operation, headers):
+    try:
+        response = requests.get(OPERATION_URL.format(GATEWAY_PORT=GATEWAY_PORT, id=operation["id"]), headers=headers)
+        response.raise_for_status()
+        return response.json()["operation"]
+    except requests.exceptions.RequestException:
+        return operation
+
 import validators
 def get_image_url(image_url):
     if image_url == "" or not validators.url(image_url):
@@ -262,12 +287,15 @@ def display_main_content():
     
     st.header("Search")
     search_query = st.text_input("Search for artists or albums", "")
+    search_sort = st.selectbox("Sort by", ["relevance", "name", "price", "price_desc", "newest"])
+    not_purchased = "jwt_token" in st.session_state and st.checkbox("Hide purchased albums")
     
     if search_query:
-        search_results = search(search_query)
+        search_results = search(search_query, search_sort, not_purchased)
 
         if search_results:
             st.header("Search Results")
+            matches = {(m["entity"], m["id"]): m for m in search_results.get("matches", [])}
 
             if search_results.get("artists"):
                 st.subheader("Artists")
@@ -275,6 +303,9 @@ def display_main_content():
                 for i, artist in enumerate(search_results["artists"]):
                     with artist_columns[i % 5]:
                         st.image(get_image_url(artist["imageURL"]), caption=artist["name"], use_container_width=True)
+                        match = matches.get(("artist", artist["id"]))
+                        if match and match["field"] != "name":
+                            st.caption(f"Matched {match['field']}: {match['value']}")
                         link = f"[View Artist Profile](?entity=artists&id={artist['id']})"
                         st.markdown(link, unsafe_allow_html=True)
                         
@@ -284,6 +315,9 @@ def display_main_content():
                 for i, album in enumerate(search_results["albums"]):
                     with album_columns[i % 5]:
                         st.image(get_image_url(album["imageURL"]), caption=album["name"], use_container_width=True)
+                        match = matches.get(("album", album["id"]))
+                        if match and match["field"] != "name":
+                            st.caption(f"Matched {match['field']}: {match['value']}")
                         link = f"[View Album Profile](?entity=albums&id={album['id']})"
                         st.markdown(link, unsafe_allow_html=True)
 
@@ -321,6 +355,13 @@ def display_main_content():
                     if "is_admin" in st.session_state and st.session_state["is_admin"] == True:
                         st.button("Delete", on_click=delete_album, args=(album['id'], ), key=f"delete_{album['id']}")
 
+    chart = fetch_chart()
+    if chart:
+        st.header("Bestsellers of the week")
+        for entry in chart:
+            album = entry["album"]
+            st.markdown(f"{entry['position']}. [{album['name']}](?entity=albums&id={album['id']}) - {entry['purchases']} purchases")
+
 def display_profile():
     query_params = st.query_params
     selected_entity = query_params.get("entity", [None])
@@ -344,7 +385,7 @@ def display_profile():
                         for album, col in zip(row, album_columns):
                             with col:
                                 st.image(get_image_url(album["imageURL"]), caption=album["name"], width=150)
-                                st.write(f"**Price:** ${album['price']:.2f}")
+                                st.write(f"**Price:** {format_money(album['price'])}")
                                 link = f"[View Album Profile](?entity=albums&id={album['id']})"
                                 st.markdown(link, unsafe_allow_html=True)
                 else:
@@ -357,7 +398,7 @@ def display_profile():
                 st.image(album["imageURL"], width=150)
                 st.write(f"**Author:** {album['author']['name']} ({album['author']['genre']})")
                 st.image(album["author"]["imageURL"], caption="Author Image", width=150)
-                st.write(f"**Price:** ${album['price']:.2f}")
+                st.write(f"**Price:** {format_money(album['price'])}")
                 st.subheader("Tracks")
                 for track in album["tracks"]:
                     st.write(f"{track['number']}. {track['name']}")
@@ -5,17 +5,17 @@ from main import GATEWAY_PORT
 
 DEPOSIT_URL = "http://localhost:{GATEWAY_PORT}/deposit"
 
-from main import get_authorization_header
+from main import get_authorization_header, wait_for_operation
 
 def deposit_money(amount, headers):
-    payload = {"money": amount}
+    payload = {"money": f"{amount:.2f}"}
     try:
         response = requests.post(DEPOSIT_URL.format(GATEWAY_PORT=GATEWAY_PORT), json=payload, headers=headers)
         response.raise_for_status()
-        return True
+        return wait_for_operation(response.json()["operation"], headers)
     except requests.exceptions.RequestException as e:
         st.error(f"Error depositing money: {e}")
-        return False
+        return None
 
 def display_deposit_page():
     st.title("Deposit Money")
@@ -25,14 +25,19 @@ def display_deposit_page():
         st.error("You must be logged in to deposit money.")
         return False
     
-    amount = st.number_input("Enter the amount to deposit:", min_value=1, step=1)
+    amount = st.number_input("Enter the amount to deposit:", min_value=0.01, step=0.01, format="%.2f")
 
     if st.button("Deposit"):
         if amount > 0:
-            if deposit_money(amount, headers):
-                st.success(f"Deposit of ${amount} process has been started. Wait for completion in notifications!")
-            else:
+            operation = deposit_money(amount, headers)
+            if operation is None:
                 st.write("Failed to start transaction!")
+            elif operation["status"] == "succeeded":
+                st.success(f"${amount:.2f} has been deposited!")
+            elif operation["status"] == "failed":
+                st.error(f"Deposit has failed: {operation.get('reason', 'Unknown error')}")
+            else:
+                st.success(f"Deposit of ${amount:.2f} process has been started. Wait for completion in notifications!")
         else:
             st.error("Please enter a valid amount greater than zero.")
 
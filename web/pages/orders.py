@@ -1,7 +1,7 @@
 import requests
 import streamlit as st
 
-from main import get_authorization_header, GATEWAY_PORT
+from main import get_authorization_header, format_money, wait_for_operation, GATEWAY_PORT
 
 ORDERS_URL = "http://localhost:{GATEWAY_PORT}/orders"
 
@@ -27,8 +27,15 @@ def buy_order():
     try:
         response = requests.post(url, headers=headers)
 
-        if response.status_code == 200:
-            st.success(f"Order buying process has been started! Wait for info in notifications!")
+        if response.status_code in (200, 202):
+            operation = wait_for_operation(response.json()["operation"], headers)
+            if operation["status"] == "failed":
+                st.error(f"Failed to buy order: {operation.get('reason', 'Unknown error')}")
+                return
+            if operation["status"] == "succeeded":
+                st.success("Order has been bought!")
+            else:
+                st.success("Order buying process has been started! Wait for info in notifications!")
             if "order" in st.session_state:
                 st.session_state["order"].clear()
             st.session_state["bought"] = True
@@ -41,7 +48,7 @@ def buy_order():
 def display_order(order):
     if isinstance(order, dict):
         st.subheader(f"Order ID: {order['id']}")
-        st.write(f"**Total Price:** ${order['totalPrice']:.2f}")
+        st.write(f"**Total Price:** {format_money(order['totalPrice'])}")
         st.write(f"**Is Paid:** {'Yes' if order['isPaid'] else 'No'}")
         st.write(f"**Date:** {order['date']}")
         
@@ -2,7 +2,7 @@ import re
 import streamlit as st
 import requests
 
-from main import chunked, get_image_url, fetch_orders, GATEWAY_PORT
+from main import chunked, format_money, get_image_url, fetch_orders, GATEWAY_PORT
 
 PROFILE_URL = "http://localhost:{GATEWAY_PORT}/profile"
 LOGS_URL = "http://localhost:{GATEWAY_PORT}/admin-panel/logs/{page_number}?pageSize={page_size}"
@@ -100,7 +100,7 @@ def display_user_profile():
             st.image(get_image_url(user["imageURL"]), width=150)
             st.write(f"**Email:** {user['email']}")
             st.write(f"**Role:** {'Admin' if user.get('isAdmin') else 'User'}")
-            st.write(f"**Balance:** {user['balance']}")
+            st.write(f"**Balance:** {format_money(user['balance'])}")
 
             if user_data.get("purchasedAlbums"):
                 st.subheader("My Albums")